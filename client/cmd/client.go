@@ -84,6 +84,110 @@ func (c client) stop(svc string) (err error) {
 	return
 }
 
+func (c client) enable(svc string) (err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	_, err = c.c.Enable(context.Background(), is)
+
+	return
+}
+
+func (c client) disable(svc string) (err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	_, err = c.c.Disable(context.Background(), is)
+
+	return
+}
+
+func (c client) mask(svc string) (err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	_, err = c.c.Mask(context.Background(), is)
+
+	return
+}
+
+func (c client) unmask(svc string) (err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	_, err = c.c.Unmask(context.Background(), is)
+
+	return
+}
+
+func (c client) restart(svc string, mode vinit.RestartMode) (err error) {
+	r := &vinit.RestartRequest{
+		Service: &vinit.Service{
+			Name: svc,
+		},
+		Mode: mode,
+	}
+
+	_, err = c.c.Restart(context.Background(), r)
+
+	return
+}
+
+func (c client) runNow(svc string) (err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	_, err = c.c.RunNow(context.Background(), is)
+
+	return
+}
+
+func (c client) history(svc string) (runs []*vinit.Run, err error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	h, err := c.c.History(context.Background(), is)
+	if err != nil {
+		return
+	}
+
+	return h.Runs, nil
+}
+
+func (c client) listServices(r *vinit.ListServicesRequest) ([]*vinit.ServiceInfo, error) {
+	l, err := c.c.ListServices(context.Background(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Services, nil
+}
+
+func (c client) showConfig(svc string) (*vinit.ServiceConfigMessage, error) {
+	is := &vinit.Service{
+		Name: svc,
+	}
+
+	return c.c.ShowConfig(context.Background(), is)
+}
+
+func (c client) serviceLogs(svc string, lines int) (*vinit.ServiceLogsMessage, error) {
+	r := &vinit.ServiceLogsRequest{
+		Service: &vinit.Service{
+			Name: svc,
+		},
+		Lines: uint32(lines),
+	}
+
+	return c.c.ServiceLogs(context.Background(), r)
+}
+
 func (c client) status(svc string) (status *vinit.ServiceStatus, err error) {
 	is := &vinit.Service{
 		Name: svc,
@@ -92,9 +196,14 @@ func (c client) status(svc string) (status *vinit.ServiceStatus, err error) {
 	return c.c.Status(context.Background(), is)
 }
 
-func (c client) systemStatus() (statuses []*vinit.ServiceStatus, err error) {
+func (c client) systemStatus() (state *vinit.SystemStateMessage, statuses []*vinit.ServiceStatus, err error) {
 	statuses = make([]*vinit.ServiceStatus, 0)
 
+	state, err = c.c.SystemState(context.Background(), new(emptypb.Empty))
+	if err != nil {
+		return
+	}
+
 	sc, err := c.c.SystemStatus(context.Background(), new(emptypb.Empty))
 	if err != nil {
 		return
@@ -118,23 +227,61 @@ func (c client) systemStatus() (statuses []*vinit.ServiceStatus, err error) {
 	return
 }
 
-func (c client) version() (out string, err error) {
-	v, err := c.c.Version(context.Background(), new(emptypb.Empty))
+func (c client) version() (*vinit.VersionMessage, error) {
+	return c.c.Version(context.Background(), new(emptypb.Empty))
+}
+
+func (c client) systemInfo() (*vinit.SystemInfoMessage, error) {
+	return c.c.SystemInfo(context.Background(), new(emptypb.Empty))
+}
+
+func (c client) reboot(r *vinit.ShutdownRequest) (err error) {
+	_, err = c.c.ScheduleReboot(context.Background(), r)
+
+	return
+}
+
+func (c client) shutdown(r *vinit.ShutdownRequest) (err error) {
+	_, err = c.c.ScheduleShutdown(context.Background(), r)
+
+	return
+}
+
+func (c client) kexecLoad(kernel, initrd, cmdline string) (err error) {
+	r := &vinit.KexecRequest{
+		Cmdline: cmdline,
+	}
+
+	// vinit opens these files itself, and so needs absolute paths
+	r.Kernel, err = filepath.Abs(kernel)
 	if err != nil {
 		return
 	}
 
-	return formatVersion(true, v.Ref, v.BuildUser, v.BuiltOn), nil
+	if initrd != "" {
+		r.Initrd, err = filepath.Abs(initrd)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = c.c.KexecLoad(context.Background(), r)
+
+	return
 }
 
-func (c client) reboot() (err error) {
-	_, err = c.c.Reboot(context.Background(), new(emptypb.Empty))
+func (c client) cancelShutdown() (err error) {
+	_, err = c.c.CancelShutdown(context.Background(), new(emptypb.Empty))
 
 	return
 }
 
-func (c client) shutdown() (err error) {
-	_, err = c.c.Shutdown(context.Background(), new(emptypb.Empty))
+func (c client) isolate(target string) (err error) {
+	t := &vinit.Target{
+		Name: target,
+	}
+
+	_, err = c.c.Isolate(context.Background(), t)
 
 	return
 }
@@ -161,8 +308,8 @@ func (c client) readConfigs() (err error) {
 	return
 }
 
-func (c client) systemLogs() (logs []string, err error) {
-	logs = make([]string, 0)
+func (c client) systemLogs() (logs []*vinit.LogMessage, err error) {
+	logs = make([]*vinit.LogMessage, 0)
 
 	sc, err := c.c.SystemLogs(context.Background(), new(emptypb.Empty))
 	if err != nil {
@@ -188,7 +335,7 @@ func (c client) systemLogs() (logs []string, err error) {
 		// I'm hesitant to break from this for{} early just in case
 		// there are some logs which print empty
 		if m.Line != "" {
-			logs = append(logs, m.Line)
+			logs = append(logs, m)
 		}
 	}
 
@@ -0,0 +1,97 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history service",
+	Short: "Show the past runs of a service",
+	Long: `Show the past runs of a service, oldest first
+
+The number of runs kept, and whether they're kept across reboots, is
+set in the [history] section of the system config`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		c, err := newClient(socketAddr)
+		if err != nil {
+			return
+		}
+
+		runs, err := c.history(args[0])
+		if err != nil {
+			return
+		}
+
+		return printOutput(runs, func() {
+			fmt.Print(formatHistory(runs))
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+func formatHistory(runs []*vinit.Run) string {
+	sb := new(strings.Builder)
+
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tENDED\tPID\tEXIT\tSIGNAL\tREASON\tRESTARTS\tERROR")
+
+	for _, r := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%d\t%s\n",
+			historyTime(r.StartTime.AsTime()), historyTime(r.EndTime.AsTime()),
+			r.Pid, r.ExitStatus, r.Signal, r.Reason, r.Restarts, r.Error,
+		)
+	}
+
+	w.Flush() // #nosec: G104
+
+	return sb.String()
+}
+
+func historyTime(t time.Time) string {
+	if t.IsZero() || t.Unix() == 0 {
+		return "-"
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
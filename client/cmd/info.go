@@ -0,0 +1,79 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show how vinit was booted",
+	Long: `Show how vinit was booted
+
+Boot options are the vinit.* parameters passed on the kernel command line,
+such as vinit.target=rescue, and apply to that boot only.
+`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		c, err := newClient(socketAddr)
+		if err != nil {
+			return
+		}
+
+		info, err := c.systemInfo()
+		if err != nil {
+			return
+		}
+
+		return printOutput(info, func() {
+			fmt.Print(formatSystemInfo(info))
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+func formatSystemInfo(i *vinit.SystemInfoMessage) string {
+	o := i.GetBootOptions()
+
+	return fmt.Sprintf("Boot Options\n---\nTarget: %s\nMask: %s\nDebug: %v\nService Directory: %s\n\nIn Effect\n---\nTarget: %s\nDebug: %v\nService Directory: %s\n",
+		o.GetTarget(), strings.Join(o.GetMask(), ", "), o.GetDebug(), o.GetSvcDir(),
+		i.Target, i.Debug, i.SvcDir,
+	)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+)
+
+var listRequest = new(vinit.ListServicesRequest)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List services by group, in boot order",
+	Long: `List services by group, in boot order
+
+Groups are listed in the order they're booted, followed by groups which
+aren't booted, and finally services whose config couldn't be loaded`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		c, err := newClient(socketAddr)
+		if err != nil {
+			return
+		}
+
+		services, err := c.listServices(listRequest)
+		if err != nil {
+			return
+		}
+
+		return printOutput(services, func() {
+			fmt.Print(formatServiceTree(services))
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolVar(&listRequest.FailedOnly, "failed", false, "only list services which failed to load, or whose last run failed")
+	listCmd.Flags().StringVar(&listRequest.Type, "type", "", "only list services of this type; one of service, cron, oneoff, or getty")
+	listCmd.Flags().StringVar(&listRequest.Group, "group", "", "only list services in this group")
+}
+
+// formatServiceTree lists services beneath their groups. services
+// are expected in the order ListServices returns them, which keeps
+// each group's services together
+func formatServiceTree(services []*vinit.ServiceInfo) string {
+	sb := new(strings.Builder)
+
+	for i := 0; i < len(services); {
+		group := services[i].Group
+
+		j := i
+		for j < len(services) && services[j].Group == group {
+			j++
+		}
+
+		sb.WriteString(groupHeading(services[i]) + "\n")
+
+		w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+		for k, svc := range services[i:j] {
+			branch := "├──"
+			if k == j-i-1 {
+				branch = "└──"
+			}
+
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", branch, svc.Name, svc.Type, serviceState(svc))
+		}
+
+		w.Flush() // #nosec: G104
+
+		i = j
+	}
+
+	return sb.String()
+}
+
+func groupHeading(svc *vinit.ServiceInfo) string {
+	switch {
+	case svc.Group == "":
+		return color.HiRedString("(failed to load)")
+
+	case svc.GroupPosition < 0:
+		return svc.Group + " " + color.HiBlackString("(not booted)")
+	}
+
+	return fmt.Sprintf("%d. %s", svc.GroupPosition+1, svc.Group)
+}
+
+func serviceState(svc *vinit.ServiceInfo) string {
+	switch {
+	case svc.LoadError != "":
+		return color.HiRedString("failed to load") + ": " + svc.LoadError
+
+	case svc.Running:
+		return color.HiGreenString("running")
+
+	case svc.Failed:
+		return color.HiRedString("failed")
+	}
+
+	return color.HiBlackString("not running")
+}
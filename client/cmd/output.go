@@ -0,0 +1,183 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var (
+	// outputFormat is set by the global --output flag
+	outputFormat string
+
+	// protoMarshaller encodes messages with the field names used in
+	// dispatcher.proto, including unset fields, so that machine
+	// readable output has a stable shape
+	protoMarshaller = protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+	}
+)
+
+// exitStatus is returned by commands which talked to vinit just fine,
+// but which should still exit non-zero, such as the status of a service
+// which isn't running. Its output has already been printed
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// exitWith returns code as an exitStatus, silencing the error and usage
+// cobra would otherwise print, since cmd has printed its result already
+func exitWith(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	return exitStatus(code)
+}
+
+// validateOutput ensures --output is one of the formats we know
+func validateOutput() error {
+	switch outputFormat {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+
+	return fmt.Errorf("invalid output %q; must be in set (%q,%q,%q)",
+		outputFormat, outputTable, outputJSON, outputYAML)
+}
+
+// printOutput prints v in the format chosen with --output. v may be a
+// proto.Message, or a slice or map of them, which are encoded with the
+// field names from dispatcher.proto.
+//
+// For table output, table is called instead, which should print v
+// for humans
+func printOutput(v interface{}, table func()) (err error) {
+	if outputFormat == outputTable {
+		table()
+
+		return
+	}
+
+	plain, err := plainValue(reflect.ValueOf(v))
+	if err != nil {
+		return
+	}
+
+	var b []byte
+
+	switch outputFormat {
+	case outputJSON:
+		b, err = json.MarshalIndent(plain, "", "  ")
+		b = append(b, '\n')
+
+	case outputYAML:
+		b, err = yaml.Marshal(plain)
+	}
+
+	if err != nil {
+		return
+	}
+
+	fmt.Print(string(b))
+
+	return
+}
+
+// plainValue turns v into maps, slices, and scalars which encode to
+// the same json as protojson would produce for any messages within
+func plainValue(v reflect.Value) (out interface{}, err error) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return
+	}
+
+	if m, ok := v.Interface().(proto.Message); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return
+		}
+
+		var b []byte
+
+		b, err = protoMarshaller.Marshal(m)
+		if err != nil {
+			return
+		}
+
+		err = json.Unmarshal(b, &out)
+
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i], err = plainValue(v.Index(i))
+			if err != nil {
+				return
+			}
+		}
+
+		return s, nil
+
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			m[fmt.Sprint(k.Interface())], err = plainValue(v.MapIndex(k))
+			if err != nil {
+				return
+			}
+		}
+
+		return m, nil
+	}
+
+	return v.Interface(), nil
+}
@@ -31,27 +31,105 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
 )
 
 // rebootCmd represents the reboot command
 var rebootCmd = &cobra.Command{
-	Use:   "reboot",
+	Use:   "reboot [TIME] [MESSAGE...]",
 	Short: "Reboot stuff",
-	Args:  cobra.ExactArgs(0),
+	Long: `Reboot this computer, either immediately or at TIME, which takes the same forms as shutdown(8):
+
+1. "now" (the default)
+2. "+m", for m minutes from now
+3. "hh:mm", for a specific time
+
+When a MESSAGE is given, it's broadcast to logged in users.
+
+With --kexec, the machine reboots straight into a new kernel, skipping
+firmware. This kernel is either loaded with --kernel (and, optionally,
+--initrd and --cmdline) or has already been loaded with kexec(8).
+
+With --firmware, the machine reboots into its firmware setup screen, on
+EFI systems which support it. With --arg, the argument is passed to the
+kernel on reboot; what this does is platform specific, but some use it
+to pick a boot target, such as "recovery" or "bootloader"`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if exclusive(rebootFirmware, rebootArg != "", kexecReboot) > 1 {
+			return fmt.Errorf("only one of --firmware, --arg, and --kexec may be used")
+		}
+
+		when, message := whenAndMessage(args)
+
+		r, err := shutdownRequest(when, message, time.Now())
+		if err != nil {
+			return
+		}
+
 		c, err := newClient(socketAddr)
 		if err != nil {
 			return
 		}
 
-		return c.reboot()
+		switch {
+		case rebootFirmware:
+			r.RebootOptions = &vinit.RebootOptions{Mode: vinit.RebootMode_FIRMWARE}
+
+		case rebootArg != "":
+			r.RebootOptions = &vinit.RebootOptions{Mode: vinit.RebootMode_RESTART2, Argument: rebootArg}
+
+		case kexecReboot:
+			r.RebootOptions = &vinit.RebootOptions{Mode: vinit.RebootMode_KEXEC}
+
+			if kexecKernel != "" {
+				err = c.kexecLoad(kexecKernel, kexecInitrd, kexecCmdline)
+				if err != nil {
+					return
+				}
+			}
+		}
+
+		return c.reboot(r)
 	},
 }
 
+// exclusive returns how many of flags are set
+func exclusive(flags ...bool) (n int) {
+	for _, f := range flags {
+		if f {
+			n++
+		}
+	}
+
+	return
+}
+
+var (
+	rebootFirmware bool
+	rebootArg      string
+
+	kexecReboot  bool
+	kexecKernel  string
+	kexecInitrd  string
+	kexecCmdline string
+)
+
 func init() {
 	rootCmd.AddCommand(rebootCmd)
 
+	rebootCmd.Flags().BoolVar(&rebootFirmware, "firmware", false, "Reboot into the firmware setup screen")
+	rebootCmd.Flags().StringVar(&rebootArg, "arg", "", "Argument to pass to the kernel on reboot, such as \"recovery\"")
+
+	rebootCmd.Flags().BoolVar(&kexecReboot, "kexec", false, "Reboot straight into a new kernel with kexec, skipping firmware")
+	rebootCmd.Flags().StringVar(&kexecKernel, "kernel", "", "Kernel to load for --kexec")
+	rebootCmd.Flags().StringVar(&kexecInitrd, "initrd", "", "Initrd to load for --kexec")
+	rebootCmd.Flags().StringVar(&kexecCmdline, "cmdline", "", "Kernel command line for --kexec. Defaults to that of the running kernel")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
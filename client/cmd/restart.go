@@ -31,32 +31,60 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
 )
 
 // restartCmd represents the restart command
 var restartCmd = &cobra.Command{
 	Use:   "restart",
 	Short: "Restart a service",
-	Args:  cobra.ExactArgs(1),
+	Long: `Restart a service
+
+vinit stops the service, waits for it to exit, and starts it again, without
+anything else being able to start or stop the service in between.
+
+With --try, the service is only restarted if it's already running. With
+--reload, a running service is reloaded rather than restarted, while a
+stopped service is started.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if exclusive(restartTry, restartReload) > 1 {
+			return fmt.Errorf("only one of --try and --reload may be used")
+		}
+
 		c, err := newClient(socketAddr)
 		if err != nil {
 			return
 		}
 
-		err = c.stop(args[0])
-		if err != nil {
-			return
+		mode := vinit.RestartMode_RESTART_ALWAYS
+
+		switch {
+		case restartTry:
+			mode = vinit.RestartMode_TRY_RESTART
+
+		case restartReload:
+			mode = vinit.RestartMode_RELOAD_OR_RESTART
 		}
 
-		return c.start(args[0])
+		return c.restart(args[0], mode)
 	},
 }
 
+var (
+	restartTry    bool
+	restartReload bool
+)
+
 func init() {
 	rootCmd.AddCommand(restartCmd)
 
+	restartCmd.Flags().BoolVar(&restartTry, "try", false, "Only restart the service if it's already running")
+	restartCmd.Flags().BoolVar(&restartReload, "reload", false, "Reload the service if it's running, rather than restarting it")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -31,6 +31,7 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -52,6 +53,9 @@ var rootCmd = &cobra.Command{
 2. Both service and system status
 3. Shutown/ Reboot/ Halt operations
 `,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateOutput()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -59,6 +63,11 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		var es exitStatus
+		if errors.As(err, &es) {
+			os.Exit(int(es))
+		}
+
 		os.Exit(1)
 	}
 }
@@ -77,6 +86,7 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.vinit.yaml)")
 	rootCmd.PersistentFlags().StringVar(&socketAddr, "sock", viper.GetString("socket_addr"), "path to the vinit socket file")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format; one of table, json, or yaml")
 }
 
 func initConfig() {
@@ -100,6 +110,7 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		// stderr, so as not to corrupt json or yaml output
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
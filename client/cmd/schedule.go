@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// shutdownRequest builds a request to shutdown (or reboot) at when, which
+// takes the same forms as shutdown(8):
+//
+//  1. "now" (or empty) for an immediate shutdown
+//  2. "+m" for a shutdown in m minutes
+//  3. "hh:mm" for a shutdown at a specific time, today or tomorrow
+func shutdownRequest(when, message string, now time.Time) (r *vinit.ShutdownRequest, err error) {
+	r = &vinit.ShutdownRequest{
+		Message: message,
+	}
+
+	switch {
+	case when == "" || when == "now":
+
+	case strings.HasPrefix(when, "+"):
+		var mins int
+
+		mins, err = strconv.Atoi(when[1:])
+		if err != nil || mins < 0 {
+			return nil, fmt.Errorf("invalid time %q", when)
+		}
+
+		r.Delay = durationpb.New(time.Duration(mins) * time.Minute)
+
+	default:
+		var t time.Time
+
+		t, err = time.ParseInLocation("15:04", when, now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q; must be one of now, +m, or hh:mm", when)
+		}
+
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+
+		r.At = timestamppb.New(at)
+	}
+
+	return
+}
+
+// whenAndMessage splits args of the form [TIME] [MESSAGE...]
+func whenAndMessage(args []string) (when, message string) {
+	if len(args) == 0 {
+		return
+	}
+
+	return args[0], strings.Join(args[1:], " ")
+}
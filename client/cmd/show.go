@@ -0,0 +1,162 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show service",
+	Short: "Show the config of a service, as vinit sees it",
+	Long: `Show the config of a service, as vinit sees it
+
+This is the service's config with defaults applied, its group after any
+group_overrides, and its environment merged from both environment and
+environment_overrides. The values of environment variables which look
+like they hold secrets, such as API_TOKEN, are redacted`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		c, err := newClient(socketAddr)
+		if err != nil {
+			return
+		}
+
+		conf, err := c.showConfig(args[0])
+		if err != nil {
+			return
+		}
+
+		return printOutput(conf, func() {
+			fmt.Print(formatConfig(conf))
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
+
+func formatConfig(c *vinit.ServiceConfigMessage) string {
+	sb := new(strings.Builder)
+
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	row := func(k string, v interface{}) {
+		fmt.Fprintf(w, "%s\t%v\n", k, v)
+	}
+
+	row("name", c.Name)
+	row("dir", c.Dir)
+
+	if c.LoadError != "" {
+		row("load error", color.HiRedString(c.LoadError))
+		w.Flush() // #nosec: G104
+
+		return sb.String()
+	}
+
+	row("type", c.Type)
+
+	group := c.Group
+	if c.Group != c.ConfiguredGroup {
+		group += fmt.Sprintf(" (overridden from %s)", c.ConfiguredGroup)
+	}
+
+	row("group", group)
+	row("runs as", fmt.Sprintf("%s:%s (uid: %d, gid: %d)", c.User, c.UserGroup, c.Uid, c.Gid))
+
+	bin := c.Bin
+	if c.BinTarget != "" && c.BinTarget != c.Bin {
+		bin += " -> " + c.BinTarget
+	}
+
+	row("bin", bin)
+	row("args", strings.Join(c.Args, " "))
+	row("working dir", c.Wd)
+	row("log dir", c.LogDir)
+	row("ignore output", c.IgnoreOutput)
+	row("reload signal", c.ReloadSignal)
+	row("stop signal", c.StopSignal)
+	row("stop timeout", c.StopTimeout.AsDuration())
+	row("critical", c.Critical)
+
+	if c.Timeout != nil {
+		row("timeout", fmt.Sprintf("%s (then %s)", c.Timeout.AsDuration(), c.TimeoutSignal))
+	}
+
+	if len(c.ValidExitCodes) > 0 {
+		row("valid exit codes", strings.Trim(fmt.Sprint(c.ValidExitCodes), "[]"))
+	}
+
+	if cr := c.Cron; cr != nil {
+		row("schedule", cr.Schedule)
+		row("time zone", cr.TimeZone)
+		row("random delay", cr.RandomDelay.AsDuration())
+		row("overlap", cr.Overlap)
+		row("catch up", cr.CatchUp)
+	}
+
+	if g := c.Getty; g != nil {
+		row("tty", strings.Join(g.Tty, ", "))
+		row("baud", g.Baud)
+		row("term", g.Term)
+	}
+
+	if s := c.Socket; s != nil {
+		row("listen", strings.Join(s.Listen, ", "))
+		row("lazy", s.Lazy)
+	}
+
+	if t := c.Trigger; t != nil {
+		row("trigger paths", strings.Join(t.Path, ", "))
+		row("trigger on boot", t.OnBoot.AsDuration())
+		row("trigger interval", t.Interval.AsDuration())
+	}
+
+	for i, kv := range c.Environment {
+		k := ""
+		if i == 0 {
+			k = "environment"
+		}
+
+		row(k, kv)
+	}
+
+	w.Flush() // #nosec: G104
+
+	return sb.String()
+}
@@ -31,21 +31,37 @@ POSSIBILITY OF SUCH DAMAGE.
 package cmd
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
 // shutdownCmd represents the shutdown command
 var shutdownCmd = &cobra.Command{
-	Use:   "shutdown",
+	Use:   "shutdown [TIME] [MESSAGE...]",
 	Short: "Shutdown this computer",
-	Args:  cobra.ExactArgs(0),
+	Long: `Shutdown this computer, either immediately or at TIME, which takes the same forms as shutdown(8):
+
+1. "now" (the default)
+2. "+m", for m minutes from now
+3. "hh:mm", for a specific time
+
+When a MESSAGE is given, it's broadcast to logged in users`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		when, message := whenAndMessage(args)
+
+		r, err := shutdownRequest(when, message, time.Now())
+		if err != nil {
+			return
+		}
+
 		c, err := newClient(socketAddr)
 		if err != nil {
 			return
 		}
 
-		return c.shutdown()
+		return c.shutdown(r)
 	},
 }
 
@@ -40,11 +40,25 @@ import (
 	vinit "github.com/vinyl-linux/vinit/dispatcher"
 )
 
+const (
+	// statusNotRunning is the exit status of `vinitctl status` for a
+	// service which isn't running, as per the LSB init script spec
+	statusNotRunning = 3
+
+	// statusBootFailed is the exit status of `vinitctl status` when
+	// a critical service failed to start on boot
+	statusBootFailed = 1
+)
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Status a service",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Status a service, or the whole system where no service is given
+
+Exits 3 when the service given isn't running, and 1 when a critical
+service failed to start on boot`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		c, err := newClient(socketAddr)
 		if err != nil {
@@ -55,15 +69,39 @@ var statusCmd = &cobra.Command{
 
 		if len(args) == 0 {
 			// systemstatus
-			var ss []*vinit.ServiceStatus
+			var (
+				state *vinit.SystemStateMessage
+				ss    []*vinit.ServiceStatus
+			)
+
+			state, ss, err = c.systemStatus()
+			if err != nil {
+				return
+			}
 
-			ss, err = c.systemStatus()
+			err = printOutput(map[string]interface{}{"system": state, "services": ss}, func() {
+				if state.GetTarget() != "" {
+					fmt.Printf("target: %s\n\n", state.Target)
+				}
+
+				if state.GetBootFailure() != nil {
+					fmt.Println(fmtBootFailure(state.BootFailure))
+				}
+
+				if state.GetPendingShutdown() != nil {
+					fmt.Println(fmtPendingShutdown(state.PendingShutdown))
+				}
+
+				for _, status = range ss {
+					fmt.Println(fmtStatus(status))
+				}
+			})
 			if err != nil {
 				return
 			}
 
-			for _, status = range ss {
-				fmt.Println(fmtStatus(status))
+			if state.GetBootFailure() != nil {
+				return exitWith(cmd, statusBootFailed)
 			}
 
 			return
@@ -75,7 +113,16 @@ var statusCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println(fmtStatus(status))
+		err = printOutput(status, func() {
+			fmt.Println(fmtStatus(status))
+		})
+		if err != nil {
+			return
+		}
+
+		if !status.Running {
+			return exitWith(cmd, statusNotRunning)
+		}
 
 		return
 	},
@@ -86,13 +133,29 @@ func init() {
 }
 
 func fmtStatus(s *vinit.ServiceStatus) string {
-	return fmt.Sprintf("%s: %s\n%s %s\n%s",
-		s.Svc.Name, runningStr(s.Running, s.Pid),
+	return fmt.Sprintf("%s: %s%s\n%s %s\n%s",
+		s.Svc.Name, runningStr(s.Running, s.Pid), stateStr(s),
 		startStr(s.StartTime.AsTime()), endStr(s.EndTime.AsTime()),
 		completionDetails(s),
 	)
 }
 
+func fmtBootFailure(b *vinit.BootFailure) string {
+	return fmt.Sprintf("%s: critical service %s in group %s failed at %s (action: %s)\n%s\n",
+		color.HiRedString("boot failed"), b.Service, b.Group, b.Time.AsTime(), b.Action,
+		b.Error,
+	)
+}
+
+func fmtPendingShutdown(p *vinit.PendingShutdown) string {
+	out := fmt.Sprintf("%s: %s at %s\n", color.HiYellowString("shutdown scheduled"), p.Action, p.At.AsTime())
+	if p.Message != "" {
+		out += p.Message + "\n"
+	}
+
+	return out
+}
+
 func runningStr(b bool, pid uint32) string {
 	if b {
 		return color.HiGreenString("running") + fmt.Sprintf(" (pid: %d)", int(pid))
@@ -101,6 +164,17 @@ func runningStr(b bool, pid uint32) string {
 	return color.HiBlackString("not running")
 }
 
+func stateStr(s *vinit.ServiceStatus) string {
+	switch {
+	case s.Masked:
+		return " " + color.HiRedString("(masked)")
+	case s.Disabled:
+		return " " + color.HiYellowString("(disabled)")
+	}
+
+	return ""
+}
+
 func startStr(t time.Time) string {
 	return fmt.Sprintf("started at %s", t)
 }
@@ -116,8 +190,54 @@ func endStr(t time.Time) string {
 func completionDetails(s *vinit.ServiceStatus) string {
 	sb := new(strings.Builder)
 
-	if s.Success || s.ExitStatus != 0 {
-		sb.WriteString("last exit status " + fmt.Sprint(s.ExitStatus) + "\n")
+	if s.LoadError != "" {
+		sb.WriteString(color.HiRedString("config could not be loaded: ") + s.LoadError + "\n")
+	}
+
+	if len(s.Listen) > 0 {
+		sb.WriteString("listening on " + strings.Join(s.Listen, ", ") + "\n")
+	}
+
+	if s.Schedule != "" {
+		sb.WriteString("runs on schedule " + s.Schedule + "\n")
+	}
+
+	if s.NextRun != nil {
+		sb.WriteString(fmt.Sprintf("next run at %s\n", s.NextRun.AsTime()))
+	}
+
+	if s.LastRun != nil {
+		sb.WriteString(fmt.Sprintf("last run at %s\n", s.LastRun.AsTime()))
+	}
+
+	if s.TriggerReason != "" {
+		sb.WriteString(fmt.Sprintf("last triggered by %s at %s\n", s.TriggerReason, s.LastTrigger.AsTime()))
+	}
+
+	if s.Restarts > 0 {
+		sb.WriteString(fmt.Sprintf("restarted %d times\n", s.Restarts))
+	}
+
+	if s.TimedOut {
+		sb.WriteString(color.HiRedString("timed out") + "\n")
+	}
+
+	switch {
+	case s.Signal != "":
+		sb.WriteString("killed by " + s.Signal)
+
+		if s.CoreDumped {
+			sb.WriteString(" (core dumped)")
+		}
+
+		sb.WriteString("\n")
+
+	case s.Success || s.ExitCode != 0:
+		sb.WriteString("last exit status " + fmt.Sprint(s.ExitCode) + "\n")
+	}
+
+	if usage := usageStr(s); usage != "" {
+		sb.WriteString(usage + "\n")
 	}
 
 	if s.Error != "" {
@@ -126,3 +246,35 @@ func completionDetails(s *vinit.ServiceStatus) string {
 
 	return sb.String()
 }
+
+// usageStr describes the resources used by the last run, should there
+// have been one
+func usageStr(s *vinit.ServiceStatus) string {
+	user := s.UserTime.AsDuration()
+	system := s.SystemTime.AsDuration()
+
+	if user == 0 && system == 0 && s.MaxRss == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("cpu time %s (user %s, system %s), max rss %s",
+		user+system, user, system, bytesStr(s.MaxRss),
+	)
+}
+
+// bytesStr formats b in the largest binary unit it fills
+func bytesStr(b uint64) string {
+	const unit = 1024
+
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
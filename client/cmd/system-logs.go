@@ -52,11 +52,11 @@ var systemLogsCmd = &cobra.Command{
 			return
 		}
 
-		for _, line := range logs {
-			fmt.Println(line)
-		}
-
-		return
+		return printOutput(logs, func() {
+			for _, m := range logs {
+				fmt.Println(m.Line)
+			}
+		})
 	},
 }
 
@@ -0,0 +1,262 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	sysvPoweroff = "poweroff"
+	sysvReboot   = "reboot"
+	sysvHalt     = "halt"
+	sysvCancel   = "cancel"
+)
+
+// sysvCmd represents the sysv command, which the shims in shims/ call
+var sysvCmd = &cobra.Command{
+	Use:   "sysv shutdown|reboot|halt|poweroff [ARGS...]",
+	Short: "Run shutdown, reboot, halt, or poweroff with sysvinit style arguments",
+	Long: `Run shutdown, reboot, halt, or poweroff with sysvinit style arguments.
+
+This command exists so that scripts which expect the sysvinit versions of
+these commands, such as 'shutdown -r +5 "kernel upgrade"' or 'shutdown -c',
+keep working, and is what the vinit shims call`,
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Args:               cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		switch args[0] {
+		case "shutdown":
+			return sysvShutdown(args[1:])
+
+		case sysvReboot, sysvHalt, sysvPoweroff:
+			return sysvRebootCmd(args[0], args[1:])
+		}
+
+		return fmt.Errorf("unknown command %q", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sysvCmd)
+}
+
+// sysvShutdown implements shutdown(8)
+func sysvShutdown(args []string) (err error) {
+	action, when, message, err := parseShutdownArgs(args)
+	if err != nil {
+		return
+	}
+
+	c, err := newClient(socketAddr)
+	if err != nil {
+		return
+	}
+
+	if action == sysvCancel {
+		return c.cancelShutdown()
+	}
+
+	r, err := shutdownRequest(when, message, time.Now())
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case sysvReboot:
+		return c.reboot(r)
+	case sysvHalt:
+		return c.halt()
+	}
+
+	return c.shutdown(r)
+}
+
+// parseShutdownArgs parses the arguments to shutdown(8), which look like:
+//
+//	shutdown [-akrhPHfFnc] [-t sec] time [warning message]
+//
+// Options which make no sense for vinit are accepted and ignored
+func parseShutdownArgs(args []string) (action, when, message string, err error) {
+	action = sysvPoweroff
+
+	var i int
+
+flags:
+	for i = 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--":
+			i++
+
+			break flags
+
+		case strings.HasPrefix(arg, "--"):
+			switch arg {
+			case "--reboot":
+				action = sysvReboot
+			case "--poweroff":
+				action = sysvPoweroff
+			case "--halt":
+				action = sysvHalt
+			case "--no-wall":
+			default:
+				err = fmt.Errorf("unsupported option %q", arg)
+
+				return
+			}
+
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, f := range arg[1:] {
+				switch f {
+				case 'r':
+					action = sysvReboot
+				case 'h', 'P':
+					action = sysvPoweroff
+				case 'H':
+					action = sysvHalt
+				case 'c':
+					action = sysvCancel
+				case 't':
+					// -t sec is the delay between SIGTERM and SIGKILL, which
+					// vinit configures itself
+					i++
+				case 'a', 'f', 'F', 'n':
+				case 'k':
+					err = fmt.Errorf("warning only shutdowns (-k) are not supported")
+
+					return
+				default:
+					err = fmt.Errorf("unsupported option -%c", f)
+
+					return
+				}
+			}
+
+		default:
+			break flags
+		}
+	}
+
+	// shutdown -c takes an optional message, rather than a time, which
+	// we ignore; vinit has its own cancellation message
+	if i < len(args) && action != sysvCancel {
+		when, message = whenAndMessage(args[i:])
+	}
+
+	// vinit can only halt immediately, and so rather than quietly
+	// powering off instead we refuse to schedule a halt
+	if action == sysvHalt && when != "" && when != "now" {
+		err = fmt.Errorf("halting at a later time is not supported; use -h or -P to power off instead")
+	}
+
+	return
+}
+
+// sysvRebootCmd implements reboot(8), halt(8), and poweroff(8)
+func sysvRebootCmd(name string, args []string) (err error) {
+	action, force, noop, noSync, err := parseRebootArgs(name, args)
+	if err != nil || noop {
+		return
+	}
+
+	if force {
+		// nothing will unmount filesystems for us, so at least
+		// get everything written to disk first
+		if !noSync {
+			syscall.Sync()
+		}
+
+		switch action {
+		case sysvReboot:
+			return syscall.Reboot(syscall.LINUX_REBOOT_CMD_RESTART)
+		case sysvHalt:
+			return syscall.Reboot(syscall.LINUX_REBOOT_CMD_HALT)
+		}
+
+		return syscall.Reboot(syscall.LINUX_REBOOT_CMD_POWER_OFF)
+	}
+
+	c, err := newClient(socketAddr)
+	if err != nil {
+		return
+	}
+
+	switch action {
+	case sysvReboot:
+		return c.reboot(nil)
+	case sysvHalt:
+		return c.halt()
+	}
+
+	return c.shutdown(nil)
+}
+
+// parseRebootArgs parses the arguments to reboot(8), halt(8), and
+// poweroff(8), which look like:
+//
+//	reboot [-n] [-w] [-d] [-f] [-i] [-p] [-h]
+//
+// force means bypassing vinit altogether, noop means doing nothing
+// (such as for -w, which only writes a wtmp record), and noSync means
+// not syncing filesystems before a forced reboot
+func parseRebootArgs(name string, args []string) (action string, force, noop, noSync bool, err error) {
+	action = name
+
+	for _, arg := range args {
+		switch arg {
+		case "-f", "--force":
+			force = true
+		case "-w", "--wtmp-only":
+			noop = true
+		case "-p", "--poweroff":
+			if name == sysvHalt {
+				action = sysvPoweroff
+			}
+		case "-n", "--no-sync":
+			noSync = true
+		case "-d", "-i", "-h", "--no-wtmp", "--no-wall":
+		default:
+			err = fmt.Errorf("unsupported option %q", arg)
+
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+	clearBelow     = "\x1b[J"
+	reverseVideo   = "\x1b[7m"
+	resetVideo     = "\x1b[0m"
+
+	keyUp   = "up"
+	keyDown = "down"
+	keyTab  = "tab"
+)
+
+var errNotTerminal = errors.New("vinitctl top must be run in a terminal")
+
+// terminal is a terminal switched into a mode where keys are read as
+// they're pressed, rather than a line at a time, and where what's drawn
+// goes to the alternate screen, leaving the user's scrollback intact
+type terminal struct {
+	in  *os.File
+	out *os.File
+
+	// state is the terminal's original state, put back by restore
+	state *unix.Termios
+}
+
+func newTerminal(in, out *os.File) (t *terminal, err error) {
+	state, err := unix.IoctlGetTermios(int(in.Fd()), unix.TCGETS)
+	if err != nil {
+		return nil, errNotTerminal
+	}
+
+	raw := *state
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(int(in.Fd()), unix.TCSETS, &raw)
+	if err != nil {
+		return
+	}
+
+	t = &terminal{
+		in:    in,
+		out:   out,
+		state: state,
+	}
+
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+
+	return
+}
+
+// restore puts the terminal back as it was found
+func (t *terminal) restore() {
+	fmt.Fprint(t.out, showCursor+exitAltScreen)
+
+	unix.IoctlSetTermios(int(t.in.Fd()), unix.TCSETS, t.state) // #nosec: G104
+}
+
+// size returns the number of rows and columns the terminal has
+func (t *terminal) size() (rows, cols int) {
+	ws, err := unix.IoctlGetWinsize(int(t.out.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Row == 0 || ws.Col == 0 {
+		return 24, 80
+	}
+
+	return int(ws.Row), int(ws.Col)
+}
+
+// readKeys sends each key pressed to keys, until the terminal can no
+// longer be read from
+func (t *terminal) readKeys(keys chan<- string) {
+	defer close(keys)
+
+	buf := make([]byte, 16)
+
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		switch k := string(buf[:n]); k {
+		case "\x1b[A", "\x1bOA":
+			keys <- keyUp
+
+		case "\x1b[B", "\x1bOB":
+			keys <- keyDown
+
+		case "\t":
+			keys <- keyTab
+
+		default:
+			keys <- k
+		}
+	}
+}
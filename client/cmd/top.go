@@ -0,0 +1,601 @@
+/*
+Copyright © 2022 James Condron <james@zero-internet.org.uk>
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+package cmd
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/status"
+)
+
+const topKeys = "q quit  ↑/↓ select  s start  x stop  r restart  l reload  tab stdout/stderr"
+
+// topCmd represents the top command
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Watch services, and start, stop, restart, or reload them",
+	Long: `Watch services, and start, stop, restart, or reload them
+
+Services are shown beneath the group they're booted as part of, in boot
+order, along with their state, pid, uptime, and how often they've been
+restarted. The logs of the selected service are tailed beneath them.
+
+Keys:
+  up/down, k/j  select a service
+  s             start the selected service
+  x             stop the selected service
+  r             restart the selected service
+  l             reload the selected service
+  tab           switch between stdout and stderr
+  q             quit`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		c, err := newClient(socketAddr)
+		if err != nil {
+			return
+		}
+
+		t, err := newTerminal(os.Stdin, os.Stdout)
+		if err != nil {
+			return
+		}
+
+		defer t.restore()
+
+		return (&top{c: c, t: t}).run(topInterval)
+	},
+}
+
+var topInterval time.Duration
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().DurationVarP(&topInterval, "interval", "n", time.Second, "How often to refresh")
+}
+
+// topRow is a single service, as shown by top
+type topRow struct {
+	info   *vinit.ServiceInfo
+	status *vinit.ServiceStatus
+}
+
+type top struct {
+	c client
+	t *terminal
+
+	state *vinit.SystemStateMessage
+	rows  []topRow
+	logs  *vinit.ServiceLogsMessage
+
+	// selected is the name, rather than the index, of the selected
+	// service, so that it stays selected as services come and go
+	selected string
+	stderr   bool
+
+	// message is the outcome of the last key pressed, failed is
+	// set where that was an error, and err is set when vinit
+	// couldn't be asked for its status
+	message string
+	failed  bool
+	err     error
+}
+
+// run draws top every interval, or whenever a key is pressed or the
+// terminal resized, until q is pressed
+func (tp *top) run(interval time.Duration) error {
+	keys := make(chan string)
+	go tp.t.readKeys(keys)
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, unix.SIGWINCH)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, unix.SIGINT, unix.SIGTERM)
+
+	defer signal.Stop(resized)
+	defer signal.Stop(quit)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	tp.refresh()
+
+	for {
+		tp.draw()
+
+		select {
+		case k, ok := <-keys:
+			if !ok || k == "q" {
+				return nil
+			}
+
+			tp.handle(k)
+
+		case <-ticker.C:
+			tp.refresh()
+
+		case <-resized:
+
+		case <-quit:
+			return nil
+		}
+	}
+}
+
+// refresh asks vinit for the state of each service, and the logs of
+// the selected one
+func (tp *top) refresh() {
+	state, statuses, err := tp.c.systemStatus()
+	if err != nil {
+		tp.err = err
+
+		return
+	}
+
+	infos, err := tp.c.listServices(new(vinit.ListServicesRequest))
+	if err != nil {
+		tp.err = err
+
+		return
+	}
+
+	tp.err = nil
+	tp.state = state
+
+	byName := make(map[string]*vinit.ServiceStatus, len(statuses))
+	for _, s := range statuses {
+		byName[s.Svc.Name] = s
+	}
+
+	tp.rows = make([]topRow, len(infos))
+	for i, info := range infos {
+		tp.rows[i] = topRow{
+			info:   info,
+			status: byName[info.Name],
+		}
+	}
+
+	if tp.index() < 0 {
+		tp.selected = ""
+
+		if len(tp.rows) > 0 {
+			tp.selected = tp.rows[0].info.Name
+		}
+	}
+
+	tp.logs = nil
+
+	if tp.selected != "" {
+		rows, _ := tp.t.size()
+
+		tp.logs, err = tp.c.serviceLogs(tp.selected, rows)
+		if err != nil {
+			tp.message, tp.failed = "could not read logs: "+errorMessage(err), true
+		}
+	}
+}
+
+// index returns the index of the selected service in tp.rows, or -1
+func (tp *top) index() int {
+	for i, r := range tp.rows {
+		if r.info.Name == tp.selected {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// handle acts on the key k
+func (tp *top) handle(k string) {
+	i := tp.index()
+
+	switch k {
+	case keyUp, "k":
+		if i > 0 {
+			tp.selected = tp.rows[i-1].info.Name
+			tp.refresh()
+		}
+
+		return
+
+	case keyDown, "j":
+		if i >= 0 && i < len(tp.rows)-1 {
+			tp.selected = tp.rows[i+1].info.Name
+			tp.refresh()
+		}
+
+		return
+
+	case keyTab:
+		tp.stderr = !tp.stderr
+
+		return
+	}
+
+	if tp.selected == "" {
+		return
+	}
+
+	var (
+		err  error
+		done string
+	)
+
+	switch k {
+	case "s":
+		err, done = tp.c.start(tp.selected), "started"
+
+	case "x":
+		err, done = tp.c.stop(tp.selected), "stopped"
+
+	case "r":
+		err, done = tp.c.restart(tp.selected, vinit.RestartMode_RESTART_ALWAYS), "restarted"
+
+	case "l":
+		err, done = tp.c.reload(tp.selected), "reloaded"
+
+	default:
+		return
+	}
+
+	tp.message, tp.failed = done+" "+tp.selected, false
+	if err != nil {
+		tp.message, tp.failed = tp.selected+": "+errorMessage(err), true
+	}
+
+	tp.refresh()
+}
+
+func (tp *top) draw() {
+	rows, cols := tp.t.size()
+
+	fmt.Fprint(tp.t.out, tp.render(rows, cols))
+}
+
+// render returns top as it should be drawn on a terminal rows high and
+// cols wide
+func (tp *top) render(rows, cols int) string {
+	header := tp.header(cols)
+	message := truncate(tp.message, cols)
+	if tp.failed {
+		message = color.HiRedString(message)
+	}
+
+	footer := []string{
+		message,
+		reverseVideo + truncate(pad(topKeys, cols), cols) + resetVideo,
+	}
+
+	// split what's left between services and logs, giving
+	// services the larger share
+	space := rows - len(header) - len(footer)
+	if space < 2 {
+		space = 2
+	}
+
+	servicesHeight := space - space/3
+	logsHeight := space - servicesHeight
+
+	lines := header
+	lines = append(lines, tp.services(servicesHeight, cols)...)
+	lines = append(lines, tp.logLines(logsHeight, cols)...)
+	lines = append(lines, footer...)
+
+	sb := new(strings.Builder)
+	sb.WriteString(cursorHome)
+
+	for i, l := range lines {
+		if i >= rows {
+			break
+		}
+
+		if i > 0 {
+			sb.WriteString("\r\n")
+		}
+
+		sb.WriteString(l + clearLine)
+	}
+
+	sb.WriteString(clearBelow)
+
+	return sb.String()
+}
+
+func (tp *top) header(cols int) (lines []string) {
+	lines = []string{truncate(fmt.Sprintf("vinit  %s", time.Now().Format(time.TimeOnly)), cols)}
+
+	if tp.err != nil {
+		return append(lines, color.HiRedString(truncate("could not read status: "+errorMessage(tp.err), cols)))
+	}
+
+	if tp.state == nil {
+		return
+	}
+
+	lines[0] = truncate(fmt.Sprintf("vinit  target %s  %s", tp.state.Target, time.Now().Format(time.TimeOnly)), cols)
+
+	if b := tp.state.BootFailure; b != nil {
+		lines = append(lines, color.HiRedString(truncate(fmt.Sprintf("boot failed: critical service %s in group %s failed (action: %s)", b.Service, b.Group, b.Action), cols)))
+	}
+
+	if p := tp.state.PendingShutdown; p != nil {
+		lines = append(lines, color.HiYellowString(truncate(fmt.Sprintf("shutdown scheduled: %s at %s", p.Action, p.At.AsTime().Local().Format(time.TimeOnly)), cols)))
+	}
+
+	return
+}
+
+// services returns height lines listing services beneath their groups,
+// scrolled such that the selected service is visible
+func (tp *top) services(height, cols int) []string {
+	nameWidth := len("NAME")
+	for _, r := range tp.rows {
+		if l := len(r.info.Name); l > nameWidth {
+			nameWidth = l
+		}
+	}
+
+	lines := []string{
+		truncate(fmt.Sprintf("  %-*s  %-10s  %7s  %-9s  %8s", nameWidth, "NAME", "STATE", "PID", "UPTIME", "RESTARTS"), cols),
+	}
+
+	selectedLine := -1
+	group := "\x00"
+
+	for _, r := range tp.rows {
+		if r.info.Group != group {
+			group = r.info.Group
+			lines = append(lines, color.New(color.Bold).Sprint(truncate(groupTitle(r.info), cols)))
+		}
+
+		if r.info.Name == tp.selected {
+			selectedLine = len(lines)
+		}
+
+		lines = append(lines, serviceLine(r, nameWidth, cols, r.info.Name == tp.selected))
+	}
+
+	// keep the column headings in place, and scroll the rest
+	body := lines[1:]
+	visible := height - 1
+
+	if visible < 1 {
+		visible = 1
+	}
+
+	offset := 0
+	if selectedLine > visible {
+		offset = selectedLine - visible
+	}
+
+	if offset+visible > len(body) {
+		visible = len(body) - offset
+	}
+
+	out := append([]string{lines[0]}, body[offset:offset+visible]...)
+	for len(out) < height {
+		out = append(out, "")
+	}
+
+	return out
+}
+
+// logLines returns height lines tailing the selected service's stdout,
+// or stderr
+func (tp *top) logLines(height, cols int) []string {
+	if height < 1 {
+		return nil
+	}
+
+	stream := "stdout"
+	if tp.stderr {
+		stream = "stderr"
+	}
+
+	title := fmt.Sprintf("── %s %s ", tp.selected, stream)
+	if l := len([]rune(title)); l < cols {
+		title += strings.Repeat("─", cols-l)
+	}
+
+	lines := []string{truncate(title, cols)}
+
+	var logs []string
+	if tp.logs != nil {
+		logs = tp.logs.Stdout
+		if tp.stderr {
+			logs = tp.logs.Stderr
+		}
+	}
+
+	if len(logs) > height-1 {
+		logs = logs[len(logs)-(height-1):]
+	}
+
+	for _, l := range logs {
+		lines = append(lines, truncate(printable(l), cols))
+	}
+
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+// groupTitle is groupHeading, without colour, so that it may be
+// truncated to fit the terminal
+func groupTitle(info *vinit.ServiceInfo) string {
+	switch {
+	case info.Group == "":
+		return "(failed to load)"
+
+	case info.GroupPosition < 0:
+		return info.Group + " (not booted)"
+	}
+
+	return fmt.Sprintf("%d. %s", info.GroupPosition+1, info.Group)
+}
+
+// serviceLine returns the line describing r, in columns matching those
+// drawn by top.services
+func serviceLine(r topRow, nameWidth, cols int, selected bool) string {
+	state, colour := topState(r)
+
+	var (
+		pid      = "-"
+		uptime   = "-"
+		restarts uint32
+	)
+
+	if s := r.status; s != nil {
+		if s.Running {
+			pid = fmt.Sprint(s.Pid)
+			uptime = durationStr(time.Since(s.StartTime.AsTime()))
+		}
+
+		restarts = s.Restarts
+	}
+
+	prefix := fmt.Sprintf("  %-*s  ", nameWidth, r.info.Name)
+	suffix := fmt.Sprintf("  %7s  %-9s  %8d", pid, uptime, restarts)
+	plain := prefix + fmt.Sprintf("%-10s", state) + suffix
+
+	switch {
+	case selected:
+		return reverseVideo + truncate(pad(plain, cols), cols) + resetVideo
+
+	case len([]rune(plain)) > cols:
+		return truncate(plain, cols)
+	}
+
+	return prefix + colour(fmt.Sprintf("%-10s", state)) + suffix
+}
+
+// topState returns a word describing the state of r, and the colour
+// to show it in
+func topState(r topRow) (string, func(string, ...interface{}) string) {
+	s := r.status
+
+	switch {
+	case r.info.LoadError != "":
+		return "load error", color.HiRedString
+
+	case s != nil && s.Masked:
+		return "masked", color.HiRedString
+
+	case r.info.Running:
+		return "running", color.HiGreenString
+
+	case r.info.Failed:
+		return "failed", color.HiRedString
+
+	case s != nil && s.Disabled:
+		return "disabled", color.HiYellowString
+	}
+
+	return "stopped", color.HiBlackString
+}
+
+// durationStr formats d in its two largest units, such as 3h12m
+func durationStr(d time.Duration) string {
+	d = d.Round(time.Second)
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+
+	hours := d / time.Hour
+	d -= hours * time.Hour
+
+	minutes := d / time.Minute
+	seconds := (d - minutes*time.Minute) / time.Second
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	}
+
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// errorMessage returns the message of a gRPC error, without the code
+// and such which accompany it
+func errorMessage(err error) string {
+	return status.Convert(err).Message()
+}
+
+// printable replaces anything in s which would move the cursor, or
+// otherwise mess with the terminal
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+
+		if !unicode.IsPrint(r) {
+			return '?'
+		}
+
+		return r
+	}, s)
+}
+
+func pad(s string, n int) string {
+	if l := len([]rune(s)); l < n {
+		return s + strings.Repeat(" ", n-l)
+	}
+
+	return s
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+
+	return s
+}
@@ -34,6 +34,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	vinit "github.com/vinyl-linux/vinit/dispatcher"
 )
 
 // versionCmd represents the version command
@@ -42,7 +43,17 @@ var versionCmd = &cobra.Command{
 	Short: "Return server and client version information",
 	Long:  "Return server and client version information",
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		fmt.Println(formatVersion(false, Ref, BuildUser, BuiltOn))
+		clientVersion := &vinit.VersionMessage{
+			Ref:       Ref,
+			BuildUser: BuildUser,
+			BuiltOn:   BuiltOn,
+		}
+
+		// people care about the client version most when the
+		// server is unreachable, so show it regardless
+		if outputFormat == outputTable {
+			fmt.Println(formatVersion(false, Ref, BuildUser, BuiltOn))
+		}
 
 		client, err := newClient(socketAddr)
 		if err != nil {
@@ -54,9 +65,9 @@ var versionCmd = &cobra.Command{
 			return
 		}
 
-		fmt.Println(serverVersion)
-
-		return
+		return printOutput(map[string]interface{}{"client": clientVersion, "server": serverVersion}, func() {
+			fmt.Println(formatVersion(true, serverVersion.Ref, serverVersion.BuildUser, serverVersion.BuiltOn))
+		})
 	},
 }
 
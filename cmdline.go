@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bootOpts holds the vinit.* parameters the running kernel was
+// booted with, and is set by loadBootOptions
+var bootOpts BootOptions
+
+// BootOptions are overrides, passed on the kernel command line, which
+// apply to a single boot; namely:
+//
+//  1. vinit.target=rescue boots into the target rescue
+//  2. vinit.mask=svc,other-svc stops svc and other-svc from starting. It may be passed more than once
+//  3. vinit.debug turns on debug logging
+//  4. vinit.svc_dir=/path loads services from /path, rather than SVC_DIR
+type BootOptions struct {
+	Target string
+	Mask   []string
+	Debug  bool
+	SvcDir string
+
+	// Unknown holds any vinit.* parameters not listed above,
+	// so that they can be warned about
+	Unknown []string
+}
+
+// parseBootOptions parses the vinit.* parameters of cmdline, ignoring
+// any others. A parameter with a bad value is skipped, and returned as
+// an error, without affecting the rest
+func parseBootOptions(cmdline string) (o BootOptions, err error) {
+	o.Mask = make([]string, 0)
+	o.Unknown = make([]string, 0)
+
+	errs := make([]error, 0)
+
+	for _, param := range splitCmdline(cmdline) {
+		k, v, hasValue := strings.Cut(param, "=")
+
+		switch k {
+		case "vinit.target":
+			o.Target = v
+
+		case "vinit.mask":
+			for _, svc := range strings.Split(v, ",") {
+				if svc != "" {
+					o.Mask = append(o.Mask, svc)
+				}
+			}
+
+		case "vinit.debug":
+			debug := true
+
+			if hasValue {
+				debug, err = strconv.ParseBool(v)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("invalid value for vinit.debug %q", v))
+
+					continue
+				}
+			}
+
+			o.Debug = debug
+
+		case "vinit.svc_dir":
+			o.SvcDir = v
+
+		default:
+			if strings.HasPrefix(k, "vinit.") {
+				o.Unknown = append(o.Unknown, param)
+			}
+		}
+	}
+
+	return o, errors.Join(errs...)
+}
+
+// splitCmdline splits cmdline into parameters the way the kernel does;
+// on whitespace, except between double quotes, which are dropped.
+//
+// Unlike a shell, there are no escapes, and an unterminated quote runs
+// to the end of cmdline, and so this never fails
+func splitCmdline(cmdline string) (params []string) {
+	params = make([]string, 0)
+
+	var (
+		param   strings.Builder
+		inParam bool
+		quoted  bool
+	)
+
+	for _, r := range cmdline {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inParam = true
+
+		case unicode.IsSpace(r) && !quoted:
+			if inParam {
+				params = append(params, param.String())
+				param.Reset()
+			}
+
+			inParam = false
+
+		default:
+			param.WriteRune(r)
+			inParam = true
+		}
+	}
+
+	if inParam {
+		params = append(params, param.String())
+	}
+
+	return
+}
+
+// loadBootOptions reads cmdlineFile into bootOpts, pointing svcDir at
+// vinit.svc_dir where set
+func loadBootOptions() (err error) {
+	b, err := os.ReadFile(cmdlineFile)
+	if err != nil {
+		return
+	}
+
+	// any error here is a bad parameter, which parseBootOptions has
+	// skipped, and so the rest are still worth applying
+	bootOpts, err = parseBootOptions(string(b))
+
+	if bootOpts.SvcDir != "" {
+		svcDir = bootOpts.SvcDir
+	}
+
+	return
+}
+
+// logBootOptions logs the overrides in bootOpts, alongside any problem
+// loading them
+func logBootOptions(err error) {
+	if err != nil {
+		sugar.Warnw("problem reading kernel command line",
+			"error", err.Error(),
+		)
+	}
+
+	for _, param := range bootOpts.Unknown {
+		sugar.Warnw("ignoring unknown kernel parameter",
+			"param", param,
+		)
+	}
+
+	sugar.Infow("boot options",
+		"target", bootOpts.Target,
+		"mask", strings.Join(bootOpts.Mask, ","),
+		"debug", bootOpts.Debug,
+		"svc_dir", svcDir,
+	)
+}
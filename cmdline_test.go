@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestParseBootOptions(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		cmdline     string
+		expect      BootOptions
+		expectError bool
+	}{
+		{"no vinit parameters", "BOOT_IMAGE=/vmlinuz root=/dev/sda2 ro quiet", BootOptions{Mask: []string{}, Unknown: []string{}}, false},
+		{"target", "ro vinit.target=rescue", BootOptions{Target: "rescue", Mask: []string{}, Unknown: []string{}}, false},
+		{"masks are split and may be repeated", "vinit.mask=app,getty, vinit.mask=cron", BootOptions{Mask: []string{"app", "getty", "cron"}, Unknown: []string{}}, false},
+		{"debug without a value", "vinit.debug", BootOptions{Mask: []string{}, Debug: true, Unknown: []string{}}, false},
+		{"debug with a value", "vinit.debug=0", BootOptions{Mask: []string{}, Unknown: []string{}}, false},
+		{"quoted svc_dir", `vinit.svc_dir="/srv/vinit services"`, BootOptions{Mask: []string{}, SvcDir: "/srv/vinit services", Unknown: []string{}}, false},
+		{"unknown parameters are collected", "vinit.nonesuch=1 vinit.targett=rescue", BootOptions{Mask: []string{}, Unknown: []string{"vinit.nonesuch=1", "vinit.targett=rescue"}}, false},
+		{"quotes may surround a whole parameter", `"vinit.target=rescue" ro`, BootOptions{Target: "rescue", Mask: []string{}, Unknown: []string{}}, false},
+		{"backslashes are not escapes", `vinit.svc_dir=/srv\vinit vinit.target=rescue`, BootOptions{Target: "rescue", Mask: []string{}, SvcDir: `/srv\vinit`, Unknown: []string{}}, false},
+		{"unterminated quotes run to the end", `vinit.target=rescue vinit.svc_dir="/srv/vinit services`, BootOptions{Target: "rescue", Mask: []string{}, SvcDir: "/srv/vinit services", Unknown: []string{}}, false},
+		{"invalid debug is skipped", "vinit.debug=loads vinit.target=rescue", BootOptions{Target: "rescue", Mask: []string{}, Unknown: []string{}}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseBootOptions(test.cmdline)
+			if err == nil && test.expectError {
+				t.Errorf("expected error, received none")
+			} else if err != nil && !test.expectError {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestLoadBootOptions(t *testing.T) {
+	defer func(orig string) {
+		svcDir = orig
+		bootOpts = BootOptions{}
+	}(svcDir)
+
+	cmdlineFile = "testdata/cmdline/overrides"
+
+	err := loadBootOptions()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	t.Run("svc_dir overrides SVC_DIR", func(t *testing.T) {
+		if svcDir != "/srv/vinit services" {
+			t.Errorf("expected %q, received %q", "/srv/vinit services", svcDir)
+		}
+	})
+
+	t.Run("system info reports overrides", func(t *testing.T) {
+		d := newDispatcher()
+
+		info, err := d.SystemInfo(context.Background(), new(emptypb.Empty))
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		expect := []string{"app", "getty", "cron"}
+		if !reflect.DeepEqual(expect, info.BootOptions.Mask) {
+			t.Errorf("expected %#v, received %#v", expect, info.BootOptions.Mask)
+		}
+
+		if info.BootOptions.Target != "rescue" {
+			t.Errorf("expected %q, received %q", "rescue", info.BootOptions.Target)
+		}
+	})
+}
+
+func TestLoadBootOptions_BadParameter(t *testing.T) {
+	defer func(orig string) {
+		svcDir = orig
+		bootOpts = BootOptions{}
+	}(svcDir)
+
+	cmdlineFile = "testdata/cmdline/bad-debug"
+
+	err := loadBootOptions()
+	if err == nil {
+		t.Errorf("expected error, received none")
+	}
+
+	if bootOpts.Target != "rescue" {
+		t.Errorf("expected %q, received %q", "rescue", bootOpts.Target)
+	}
+
+	if svcDir != "/srv/vinit" {
+		t.Errorf("expected %q, received %q", "/srv/vinit", svcDir)
+	}
+}
+
+func TestLoadBootOptions_MissingCmdline(t *testing.T) {
+	cmdlineFile = "/this/path/does/not/exist/i/bloody/well/hope"
+
+	err := loadBootOptions()
+	if err == nil {
+		t.Errorf("expected error, received none")
+	}
+}
+
+func TestSupervisor_Masked(t *testing.T) {
+	d := newDispatcher()
+	d.s.bootMasks = []string{"app"}
+
+	t.Run("masked services cannot be started", func(t *testing.T) {
+		err := d.s.Start("app", false)
+		if err != errServiceMasked {
+			t.Errorf("expected %#v, received %#v", errServiceMasked, err)
+		}
+	})
+
+	t.Run("masked services are skipped on boot", func(t *testing.T) {
+		got := d.s.bootable(d.s.groupsServices["system"])
+		expect := []string{"app-cronjob", "app-oneoff"}
+
+		if !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected %#v, received %#v", expect, got)
+		}
+	})
+
+	t.Run("masking a service masks its instances", func(t *testing.T) {
+		d.s.bootMasks = []string{"getty"}
+
+		if !d.s.masked("getty@tty1") {
+			t.Error("expected getty@tty1 to be masked")
+		}
+	})
+}
@@ -1,8 +1,26 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/BurntSushi/toml"
 	"github.com/google/shlex"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	RecoveryExit_Reboot RecoveryExit = iota
+	RecoveryExit_Setup
+)
+
+const (
+	FailureAction_Abort FailureAction = iota
+	FailureAction_Continue
+	FailureAction_Recovery
+	FailureAction_Reboot
 )
 
 var (
@@ -52,10 +70,138 @@ func (s *StartupScript) UnmarshalText(text []byte) (err error) {
 	return
 }
 
+// FailureAction governs what vinit does when a critical service
+// fails to start on boot; namely:
+//
+//  1. FailureAction_Abort, represented by "abort" in config. Stop starting groups, but keep vinit running
+//  2. FailureAction_Continue, represented by "continue" in config. Carry on as if nothing happened
+//  3. FailureAction_Recovery, represented by "recovery" in config. Drop into the recovery shell
+//  4. FailureAction_Reboot, represented by "reboot" in config. Stop everything and reboot
+type FailureAction int8
+
+// UnmarshalText provides the Unmarshal interface for FailureAction
+func (f *FailureAction) UnmarshalText(text []byte) (err error) {
+	t := string(text)
+
+	switch t {
+	case "abort":
+		*f = FailureAction_Abort
+	case "continue":
+		*f = FailureAction_Continue
+	case "recovery":
+		*f = FailureAction_Recovery
+	case "reboot":
+		*f = FailureAction_Reboot
+	default:
+		err = fmt.Errorf("invalid on_failure action %q; must be in set (%q,%q,%q,%q)",
+			t, "abort", "continue", "recovery", "reboot")
+	}
+
+	return
+}
+
+// String returns the config representation of a FailureAction
+func (f FailureAction) String() string {
+	switch f {
+	case FailureAction_Continue:
+		return "continue"
+	case FailureAction_Recovery:
+		return "recovery"
+	case FailureAction_Reboot:
+		return "reboot"
+	}
+
+	return "abort"
+}
+
+// RecoveryExit governs what happens once the recovery shell exits; namely:
+//
+//  1. RecoveryExit_Reboot, represented by "reboot" in config. Reboot the system
+//  2. RecoveryExit_Setup, represented by "setup" in config. Try booting vinit again
+type RecoveryExit int8
+
+// UnmarshalText provides the Unmarshal interface for RecoveryExit
+func (r *RecoveryExit) UnmarshalText(text []byte) (err error) {
+	t := string(text)
+
+	switch t {
+	case "reboot":
+		*r = RecoveryExit_Reboot
+	case "setup":
+		*r = RecoveryExit_Setup
+	default:
+		err = fmt.Errorf("invalid on_exit action %q; must be in set (%q,%q)",
+			t, "reboot", "setup")
+	}
+
+	return
+}
+
+// Recovery configures the shell vinit drops into when boot fails
+type Recovery struct {
+	// Shell is the command to run, which defaults to the same
+	// agetty command as the default StartupScript
+	Shell *StartupScript `toml:"shell"`
+
+	// Console is the device the shell is attached to. When empty,
+	// the shell inherits vinit's own stdin/ stdout/ stderr
+	Console string `toml:"console"`
+
+	// Password, when true, requires the root password (as per
+	// /etc/shadow) before the shell is started, in the style
+	// of sulogin
+	Password bool `toml:"password"`
+
+	// OnExit governs what happens when the shell exits
+	OnExit RecoveryExit `toml:"on_exit"`
+}
+
 type Config struct {
 	Groups         []string            `toml:"groups"`
 	GroupOverrides map[string][]string `toml:"group_overrides"`
 	StartupScript  *StartupScript      `toml:"startup_script"`
+
+	// MaxParallel bounds how many services within a single group
+	// may be started at once. A value of zero (the default) means
+	// no limit, while a value of one starts services sequentially
+	MaxParallel int `toml:"max_parallel"`
+
+	// OnFailure maps groups to the action to take when a
+	// critical service in that group fails to start
+	OnFailure map[string]FailureAction `toml:"on_failure"`
+
+	Recovery Recovery `toml:"recovery"`
+
+	// Init configures the steps vinit takes to initialise the
+	// system before loading services
+	Init EarlyInit `toml:"init"`
+
+	// Signals maps signal names (such as "SIGPWR") to the action
+	// to take when vinit, as PID 1, receives that signal
+	Signals map[string]SignalAction `toml:"signals"`
+
+	Shutdown Shutdown `toml:"shutdown"`
+
+	// Targets maps target names (such as "rescue" or "multi-user")
+	// to the groups started when that target is active
+	Targets map[string][]string `toml:"targets"`
+
+	// DefaultTarget is the target booted into when none is passed
+	// on the kernel command line. When empty, every group is started
+	DefaultTarget string `toml:"default_target"`
+
+	History History `toml:"history"`
+}
+
+// Shutdown configures how long vinit gives the system to shut down
+type Shutdown struct {
+	// Timeout is the deadline for the whole shutdown sequence, after
+	// which vinit syncs and reboots (or powers off, etc.) regardless
+	Timeout time.Duration `toml:"timeout"`
+
+	// KillTimeout is how long processes are given to exit after
+	// being sent SIGTERM, before being sent SIGKILL
+	KillTimeout time.Duration `toml:"kill_timeout"`
 }
 
 func LoadConfig(fn string) (c Config, err error) {
@@ -68,6 +214,111 @@ func LoadConfig(fn string) (c Config, err error) {
 		c.StartupScript = defaultStartupScript
 	}
 
+	if c.Recovery.Shell == nil {
+		c.Recovery.Shell = defaultStartupScript
+	}
+
+	if c.Shutdown.Timeout <= 0 {
+		c.Shutdown.Timeout = defaultShutdownTimeout
+	}
+
+	if c.Shutdown.KillTimeout <= 0 {
+		c.Shutdown.KillTimeout = defaultKillTimeout
+	}
+
+	for name := range c.Signals {
+		sig := unix.SignalNum(name)
+		if sig == 0 {
+			return c, fmt.Errorf("invalid signal %q", name)
+		}
+
+		if _, ok := defaultSignalActions[sig]; !ok {
+			return c, fmt.Errorf("unhandled signal %q; must be in set (%s)", name, strings.Join(handledSignalNames(), ","))
+		}
+	}
+
+	for name, groups := range c.Targets {
+		for _, group := range groups {
+			if !contains(c.Groups, group) {
+				return c, fmt.Errorf("target %q contains unknown group %q", name, group)
+			}
+		}
+	}
+
+	if c.DefaultTarget != "" {
+		if _, ok := c.Targets[c.DefaultTarget]; !ok {
+			return c, fmt.Errorf("default_target %q does not exist", c.DefaultTarget)
+		}
+	}
+
+	return
+}
+
+// Parallelism returns the number of services in a group of size n which
+// may be started concurrently
+func (c Config) Parallelism(n int) int {
+	if c.MaxParallel > 0 && c.MaxParallel < n {
+		return c.MaxParallel
+	}
+
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// FailureActionFor returns the action to take when a critical service
+// in group fails to start.
+//
+// Where a group has no configured action, boot is aborted
+func (c Config) FailureActionFor(group string) FailureAction {
+	action, ok := c.OnFailure[group]
+	if !ok {
+		return FailureAction_Abort
+	}
+
+	return action
+}
+
+// SignalActionFor returns the action to take when vinit receives sig.
+//
+// Where sig has no configured action, the default is used
+func (c Config) SignalActionFor(sig os.Signal) SignalAction {
+	action, ok := c.Signals[signalName(sig)]
+	if !ok {
+		return defaultSignalActions[sig]
+	}
+
+	return action
+}
+
+// TargetGroups returns the groups started by target, in the order
+// they appear in c.Groups.
+//
+// An empty target means c.DefaultTarget or, where that's unset too,
+// every group
+func (c Config) TargetGroups(target string) (groups []string, err error) {
+	if target == "" {
+		target = c.DefaultTarget
+	}
+
+	if target == "" {
+		return c.Groups, nil
+	}
+
+	members, ok := c.Targets[target]
+	if !ok {
+		return nil, errTargetNotExist
+	}
+
+	groups = make([]string, 0, len(members))
+	for _, group := range c.Groups {
+		if contains(members, group) {
+			groups = append(groups, group)
+		}
+	}
+
 	return
 }
 
@@ -29,6 +29,82 @@ func TestConfig_ReconcileOverride(t *testing.T) {
 	}
 }
 
+func TestConfig_Parallelism(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		maxParallel int
+		n           int
+		expect      int
+	}{
+		{"unset means unbounded", 0, 5, 5},
+		{"max_parallel bounds large groups", 2, 5, 2},
+		{"small groups are not padded out", 10, 3, 3},
+		{"sequential start", 1, 5, 1},
+		{"empty groups still get a slot", 0, 0, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := Config{MaxParallel: test.maxParallel}.Parallelism(test.n)
+			if test.expect != got {
+				t.Errorf("expected %d, received %d", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestConfig_FailureActionFor(t *testing.T) {
+	c := Config{
+		OnFailure: map[string]FailureAction{
+			"filesystems": FailureAction_Recovery,
+			"network":     FailureAction_Continue,
+		},
+	}
+
+	for _, test := range []struct {
+		group  string
+		expect FailureAction
+	}{
+		{"filesystems", FailureAction_Recovery},
+		{"network", FailureAction_Continue},
+		{"unconfigured", FailureAction_Abort},
+	} {
+		t.Run(test.group, func(t *testing.T) {
+			got := c.FailureActionFor(test.group)
+			if test.expect != got {
+				t.Errorf("expected %q, received %q", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestFailureAction_UnmarshalText(t *testing.T) {
+	for _, test := range []struct {
+		text        string
+		expect      FailureAction
+		expectError bool
+	}{
+		{"abort", FailureAction_Abort, false},
+		{"continue", FailureAction_Continue, false},
+		{"recovery", FailureAction_Recovery, false},
+		{"reboot", FailureAction_Reboot, false},
+		{"panic", FailureAction_Abort, true},
+	} {
+		t.Run(test.text, func(t *testing.T) {
+			var f FailureAction
+
+			err := f.UnmarshalText([]byte(test.text))
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if test.expect != f {
+				t.Errorf("expected %q, received %q", test.expect, f)
+			}
+		})
+	}
+}
+
 func TestConfig_StartupScript(t *testing.T) {
 	for _, test := range []struct {
 		name        string
@@ -62,3 +138,52 @@ func TestConfig_StartupScript(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Recovery(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		fn          string
+		expect      Recovery
+		expectError bool
+	}{
+		{"Undefined recovery gets a default shell", "testdata/successing/undefined-startupscript.toml", Recovery{Shell: defaultStartupScript}, false},
+		{"Fully configured recovery", "testdata/successing/recovery.toml", Recovery{Shell: &StartupScript{cmd: "/bin/sh", args: []string{"-l"}}, Console: "/dev/ttyS0", Password: true, OnExit: RecoveryExit_Setup}, false},
+		{"Invalid on_exit errors", "testdata/erroring/invalid-recovery-exit.toml", Recovery{}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := LoadConfig(test.fn)
+
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expect, c.Recovery) {
+				t.Errorf("expected %#v, received %#v", test.expect, c.Recovery)
+			}
+		})
+	}
+}
+
+func TestConfig_Init(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		fn     string
+		expect EarlyInit
+	}{
+		{"Undefined init does nothing", "testdata/successing/undefined-startupscript.toml", EarlyInit{}},
+		{"Fully configured init", "testdata/successing/early-init.toml", EarlyInit{MountPseudoFS: true, Kmsg: true, RemountRoot: true, Hostname: true, SeedRNG: true}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := LoadConfig(test.fn)
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expect, c.Init) {
+				t.Errorf("expected %#v, received %#v", test.expect, c.Init)
+			}
+		})
+	}
+}
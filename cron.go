@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// lastRunFile, in a cron's service directory, holds the time
+	// that cron last ran, so that missed runs can be caught up on
+	// across reboots
+	lastRunFile = ".last-run"
+
+	cronSchedule = "schedule"
+	cronRunNow   = "run-now"
+	cronCatchUp  = "catch-up"
+	cronQueued   = "queued"
+)
+
+var (
+	// cronPollInterval is how often a cron's schedule is checked
+	cronPollInterval = time.Second
+
+	errNotCron     = status.Error(codes.FailedPrecondition, "service is not a cron")
+	errCronRunning = status.Error(codes.FailedPrecondition, "cron is already running")
+)
+
+// RunNow runs a cron immediately, outside of its schedule
+func (d Dispatcher) RunNow(_ context.Context, s *dispatcher.Service) (out *emptypb.Empty, err error) {
+	out = new(emptypb.Empty)
+
+	if s == nil || s.Name == "" {
+		return out, errNoService
+	}
+
+	return out, d.s.RunNow(s.Name)
+}
+
+func (s *Supervisor) RunNow(name string) error {
+	svc, ok := s.service(name)
+	if !ok {
+		return errServiceNotExist
+	}
+
+	if svc.loadError != "" {
+		return errServiceDodgyConf
+	}
+
+	if s.masked(name) {
+		return errServiceMasked
+	}
+
+	if svc.Config.Type != ServiceType_Cron {
+		return errNotCron
+	}
+
+	return svc.runCron(cronRunNow)
+}
+
+// watchSchedule runs s according to its schedule, checking whether
+// it's due every interval, until s is disarmed
+func (s *Service) watchSchedule(disarmed chan struct{}, interval time.Duration) {
+	c := s.Config.Cron
+
+	if c.CatchUp && s.missedRun() {
+		s.scheduledRun(cronCatchUp)
+	}
+
+	s.state.Lock()
+	s.nextRun = c.Next(time.Now())
+
+	// @reboot crons only run once per boot, however often
+	// they're armed
+	if c.Schedule.Reboot && s.ranOnBoot {
+		s.nextRun = time.Time{}
+	}
+	s.state.Unlock()
+
+	for {
+		select {
+		case <-disarmed:
+			return
+		case <-time.After(interval):
+		}
+
+		if reason := s.due(time.Now()); reason != "" {
+			s.scheduledRun(reason)
+		}
+	}
+}
+
+// due returns why s ought to run at now, or an empty string when it
+// oughtn't, moving its schedule on where it's run
+func (s *Service) due(now time.Time) (reason string) {
+	c := s.Config.Cron
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	switch {
+	case !s.nextRun.IsZero() && !now.Before(s.nextRun):
+		s.nextRun = time.Time{}
+
+		if c.Schedule.Reboot {
+			s.ranOnBoot = true
+		} else {
+			s.nextRun = c.Next(now)
+		}
+
+		return cronSchedule
+
+	case s.queued && s.proc == nil:
+		s.queued = false
+
+		return cronQueued
+	}
+
+	return ""
+}
+
+// nextScheduledRun returns when s is next due to run, or the zero
+// time when s isn't armed, or has nothing scheduled
+func (s *Service) nextScheduledRun() time.Time {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	if s.disarmed == nil {
+		return time.Time{}
+	}
+
+	return s.nextRun
+}
+
+// scheduledRun runs s, logging rather than returning errors, since
+// there's nobody to return them to
+func (s *Service) scheduledRun(reason string) {
+	err := s.runCron(reason)
+	if err != nil {
+		sugar.Warnw("cron did not run",
+			"service", s.Name,
+			"reason", reason,
+			"error", err.Error(),
+		)
+	}
+}
+
+// runCron runs s, should s not already be running. Where it is, the
+// cron's overlap policy decides what happens
+func (s *Service) runCron(reason string) (err error) {
+	if s.isRunning() {
+		switch s.Config.Cron.Overlap {
+		case OverlapPolicy_Skip:
+			return errCronRunning
+
+		case OverlapPolicy_Queue:
+			s.state.Lock()
+			s.queued = true
+			s.state.Unlock()
+
+			sugar.Infow("cron already running, queueing run",
+				"service", s.Name,
+				"reason", reason,
+			)
+
+			return
+		}
+	}
+
+	at := s.setTriggered(reason)
+
+	sugar.Infow("running cron",
+		"service", s.Name,
+		"reason", reason,
+	)
+
+	err = s.recordRun(at)
+	if err != nil {
+		sugar.Warnw("could not record cron run",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+
+	// Restart, rather than Start, so that OverlapPolicy_Replace
+	// can't race anything else starting s
+	return s.Restart(RestartMode_Restart)
+}
+
+// missedRun returns true when a scheduled run of s should have
+// happened since s last ran. A cron which has never run can't have
+// missed anything, nor can an @reboot cron
+func (s *Service) missedRun() bool {
+	if s.Config.Cron.Schedule.Reboot {
+		return false
+	}
+
+	last, err := s.lastRun()
+	if err != nil {
+		return false
+	}
+
+	return s.Config.Cron.Schedule.Next(last).Before(time.Now())
+}
+
+// lastRun returns when s last ran, as recorded by recordRun
+func (s *Service) lastRun() (t time.Time, err error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, lastRunFile)) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+}
+
+// recordRun persists t as the last time s ran
+func (s *Service) recordRun(t time.Time) error {
+	return os.WriteFile(filepath.Join(s.dir, lastRunFile), []byte(t.Format(time.RFC3339)+"\n"), 0600)
+}
+
+// lastRunOrZero returns lastRun, or the zero time should s have never
+// run
+func (s *Service) lastRunOrZero() time.Time {
+	t, err := s.lastRun()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		sugar.Warnw("could not read last cron run",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+
+	return t
+}
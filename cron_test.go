@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func loadCrons(t *testing.T) *Supervisor {
+	t.Helper()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/crons")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keep run times out of testdata
+	for _, svc := range s.services {
+		svc.dir = t.TempDir()
+	}
+
+	return s
+}
+
+func TestSupervisor_RunNow(t *testing.T) {
+	cronPollInterval = time.Millisecond * 10
+
+	s := loadCrons(t)
+	defer s.StopAll()
+
+	err := s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	for _, test := range []struct {
+		name          string
+		expectError   error
+		expectNewPid  bool
+		expectTrigger string
+	}{
+		{"skip-cron", errCronRunning, false, cronRunNow},
+		{"queue-cron", nil, false, cronQueued},
+		{"replace-cron", nil, true, cronRunNow},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			svc := s.services[test.name]
+
+			err := s.RunNow(test.name)
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			if !svc.isRunning() {
+				t.Fatalf("service %s should be running", test.name)
+			}
+
+			last, err := svc.lastRun()
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if time.Since(last) > time.Minute {
+				t.Errorf("expected last run to be recorded, received %s", last)
+			}
+
+			pid := statusOf(svc).Pid
+
+			err = s.RunNow(test.name)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+
+			if test.expectNewPid == (pid == statusOf(svc).Pid) {
+				t.Errorf("expected new pid to be %v (old: %d, new: %d)", test.expectNewPid, pid, statusOf(svc).Pid)
+			}
+
+			// give queued runs time to happen
+			time.Sleep(time.Millisecond * 300)
+
+			if _, by := svc.lastTrigger(); test.expectTrigger != by {
+				t.Errorf("expected %q, received %q", test.expectTrigger, by)
+			}
+		})
+	}
+}
+
+func TestService_CatchUp(t *testing.T) {
+	cronPollInterval = time.Millisecond * 10
+
+	for _, test := range []struct {
+		name          string
+		lastRun       time.Time
+		expectRunning bool
+	}{
+		{"never run", time.Time{}, false},
+		{"ran recently", time.Now(), false},
+		{"missed a run", time.Now().Add(-2 * time.Hour), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := loadCrons(t)
+			defer s.StopAll()
+
+			svc := s.services["catch-up-cron"]
+
+			if !test.lastRun.IsZero() {
+				err := svc.recordRun(test.lastRun)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := s.StartAll()
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			time.Sleep(time.Millisecond * 100)
+
+			if test.expectRunning != svc.isRunning() {
+				t.Errorf("expected running to be %v", test.expectRunning)
+			}
+
+			if _, by := svc.lastTrigger(); test.expectRunning && by != cronCatchUp {
+				t.Errorf("expected %q, received %q", cronCatchUp, by)
+			}
+		})
+	}
+}
+
+func TestSupervisor_setMarker_UnmaskCron(t *testing.T) {
+	cronPollInterval = time.Millisecond * 10
+
+	s := loadCrons(t)
+	defer s.StopAll()
+
+	svc := s.services["catch-up-cron"]
+
+	err := svc.recordRun(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = s.setMarker("catch-up-cron", maskedMarker, true)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	if !svc.nextScheduledRun().IsZero() {
+		t.Errorf("expected masked cron to be disarmed, next run is %s", svc.nextScheduledRun())
+	}
+
+	// miss a run while masked, so that the cron catches up once
+	// it's armed again
+	err = svc.recordRun(time.Now().Add(-2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.setMarker("catch-up-cron", maskedMarker, false)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if !svc.isRunning() {
+		t.Error("expected unmasked cron to be running")
+	}
+
+	if _, by := svc.lastTrigger(); by != cronCatchUp {
+		t.Errorf("expected %q, received %q", cronCatchUp, by)
+	}
+
+	if svc.nextScheduledRun().IsZero() {
+		t.Error("expected unmasked cron to be scheduled")
+	}
+}
+
+func TestDispatcher_RunNow(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		s           *dispatcher.Service
+		expectError error
+	}{
+		{"nil service", nil, errNoService},
+		{"empty service", &dispatcher.Service{}, errNoService},
+		{"unknown service", &dispatcher.Service{Name: "nonesuch"}, errServiceNotExist},
+		{"dodgy service", &dispatcher.Service{Name: "broken"}, errServiceDodgyConf},
+		{"not a cron", &dispatcher.Service{Name: "app"}, errNotCron},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.RunNow(context.Background(), test.s)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	from := time.Date(2023, time.June, 1, 11, 30, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name       string
+		fn         string
+		expect     time.Time
+		expectUpTo time.Duration
+	}{
+		{"seconds, time zone, and random delay", "testdata/successing/cron-seconds.toml", time.Date(2023, time.June, 1, 11, 30, 30, 0, time.UTC), 5 * time.Minute},
+		{"@every", "testdata/successing/cron-every.toml", from.Add(90 * time.Minute), 0},
+		{"@reboot", "testdata/successing/cron-reboot.toml", from, 30 * time.Second},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := LoadServiceConfig(test.fn)
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			next := c.Cron.Next(from)
+			if next.Before(test.expect) || next.After(test.expect.Add(test.expectUpTo)) {
+				t.Errorf("expected %s (up to %s later), received %s", test.expect, test.expectUpTo, next)
+			}
+		})
+	}
+}
+
+func TestCron_TimeZone(t *testing.T) {
+	c := ServiceConfig{Type: ServiceType_Cron, Cron: &Cron{TimeZone: "Europe/London"}}
+
+	err := c.Cron.Schedule.UnmarshalText([]byte("0 0 9 * * *"))
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = c.validateCron()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	// 09:00 in London during British Summer Time is 08:00 UTC
+	expect := time.Date(2023, time.June, 2, 8, 0, 0, 0, time.UTC)
+
+	next := c.Cron.Next(time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC))
+	if !expect.Equal(next) {
+		t.Errorf("expected %s, received %s", expect, next)
+	}
+}
+
+func TestService_Reboot(t *testing.T) {
+	cronPollInterval = time.Millisecond * 10
+
+	s := loadCrons(t)
+	defer s.StopAll()
+
+	svc := s.services["reboot-cron"]
+
+	err := s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	t.Run("@reboot crons run when armed", func(t *testing.T) {
+		if !svc.isRunning() {
+			t.Error("service reboot-cron should be running")
+		}
+	})
+
+	t.Run("@reboot crons only run once", func(t *testing.T) {
+		err := s.StopAll()
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		err = s.StartAll()
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		if svc.isRunning() {
+			t.Error("service reboot-cron should not be running")
+		}
+	})
+}
+
+func TestDispatcher_Status_Cron(t *testing.T) {
+	cronPollInterval = time.Millisecond * 10
+
+	s := loadCrons(t)
+	defer s.StopAll()
+
+	err := s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	d := Dispatcher{s: s}
+
+	status, err := d.Status(context.Background(), &dispatcher.Service{Name: "skip-cron"})
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	if status.Schedule != "@yearly" {
+		t.Errorf("expected %q, received %q", "@yearly", status.Schedule)
+	}
+
+	if status.NextRun == nil || !status.NextRun.AsTime().After(time.Now()) {
+		t.Errorf("expected a next run in the future, received %v", status.NextRun)
+	}
+}
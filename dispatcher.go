@@ -4,8 +4,10 @@ import (
 	"context"
 
 	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -14,6 +16,9 @@ var (
 	errNoService        = status.Error(codes.InvalidArgument, "missing service name")
 	errServiceNotExist  = status.Error(codes.InvalidArgument, "service does not exist")
 	errServiceDodgyConf = status.Error(codes.FailedPrecondition, "service config is incorrect")
+	errServiceMasked    = status.Error(codes.FailedPrecondition, "service is masked")
+
+	errNoPendingShutdown = status.Error(codes.FailedPrecondition, "no shutdown is scheduled")
 )
 
 type Dispatcher struct {
@@ -49,18 +54,65 @@ func (d Dispatcher) Status(ctx context.Context, s *dispatcher.Service) (out *dis
 		return out, errNoService
 	}
 
-	status, err := d.s.Status(s.Name)
+	svc, ok := d.s.service(s.Name)
+	if !ok {
+		return out, errServiceNotExist
+	}
+
+	status, err := svc.Status()
 	if err != nil {
 		return
 	}
 
 	out.Svc = s
+	out.LoadError = svc.loadError
+
+	if starts := svc.startCount(); starts > 1 {
+		out.Restarts = uint32(starts - 1)
+	}
+
 	out.Running = status.Running
 	out.Pid = uint32(status.Pid)
 	out.ExitStatus = uint32(status.ExitStatus)
+	out.ExitCode = int32(status.ExitStatus)
+	out.CoreDumped = status.CoreDumped
+	out.UserTime = durationpb.New(status.UserTime)
+	out.SystemTime = durationpb.New(status.SystemTime)
+	out.MaxRss = uint64(status.MaxRSS)
+
+	if status.Signalled() {
+		out.Signal = unix.SignalName(status.Signal)
+	}
 	out.StartTime = timestamppb.New(status.StartTime)
 	out.EndTime = timestamppb.New(status.EndTime)
 	out.Success = status.Success
+	out.TimedOut = status.TimedOut
+	out.Disabled = svc.isDisabled()
+	out.Masked = d.s.masked(s.Name)
+
+	if sock := svc.Config.Socket; sock != nil {
+		out.Listen = make([]string, len(sock.Listen))
+		for i, addr := range sock.Listen {
+			out.Listen[i] = addr.String()
+		}
+	}
+
+	if at, by := svc.lastTrigger(); !at.IsZero() {
+		out.LastTrigger = timestamppb.New(at)
+		out.TriggerReason = by
+	}
+
+	if svc.Config.Type == ServiceType_Cron {
+		if last := svc.lastRunOrZero(); !last.IsZero() {
+			out.LastRun = timestamppb.New(last)
+		}
+
+		out.Schedule = svc.Config.Cron.String()
+
+		if next := svc.nextScheduledRun(); !next.IsZero() {
+			out.NextRun = timestamppb.New(next)
+		}
+	}
 
 	if status.Error != nil {
 		out.Error = status.Error.Error()
@@ -86,11 +138,11 @@ func (d Dispatcher) ReadConfigs(ctx context.Context, in *emptypb.Empty) (*emptyp
 func (d Dispatcher) SystemStatus(_ *emptypb.Empty, ds dispatcher.Dispatcher_SystemStatusServer) (err error) {
 	var status *dispatcher.ServiceStatus
 
-	for s := range d.s.services {
+	for _, l := range d.s.listServices() {
 		// ignore the context from ds.Context() because:
 		//  1. It makes testing much easier (gross); and
 		//  2. There's nothing in that context that's of any use downstream
-		status, err = d.Status(context.Background(), &dispatcher.Service{Name: s})
+		status, err = d.Status(context.Background(), &dispatcher.Service{Name: l.name})
 		if err != nil {
 			return
 		}
@@ -104,6 +156,40 @@ func (d Dispatcher) SystemStatus(_ *emptypb.Empty, ds dispatcher.Dispatcher_Syst
 	return
 }
 
+// SystemState returns the state of vinit itself; whether boot failed,
+// whether a shutdown is pending, and the active target
+func (d Dispatcher) SystemState(context.Context, *emptypb.Empty) (*dispatcher.SystemStateMessage, error) {
+	return d.systemState(), nil
+}
+
+func (d Dispatcher) systemState() (out *dispatcher.SystemStateMessage) {
+	out = new(dispatcher.SystemStateMessage)
+	out.Target = d.s.Target()
+
+	if bf := d.s.bootFailure; bf != nil {
+		out.BootFailure = &dispatcher.BootFailure{
+			Group:   bf.Group,
+			Service: bf.Service,
+			Action:  bf.Action.String(),
+			Error:   bf.Err.Error(),
+			Time:    timestamppb.New(bf.Time),
+		}
+	}
+
+	d.s.shutdownMu.Lock()
+	defer d.s.shutdownMu.Unlock()
+
+	if p := d.s.pendingShutdown; p != nil {
+		out.PendingShutdown = &dispatcher.PendingShutdown{
+			Action:  p.PowerAction.String(),
+			At:      timestamppb.New(p.At),
+			Message: p.Message,
+		}
+	}
+
+	return
+}
+
 func (d Dispatcher) Version(context.Context, *emptypb.Empty) (*dispatcher.VersionMessage, error) {
 	return &dispatcher.VersionMessage{
 		Ref:       ref,
@@ -112,6 +198,22 @@ func (d Dispatcher) Version(context.Context, *emptypb.Empty) (*dispatcher.Versio
 	}, nil
 }
 
+// SystemInfo returns the overrides vinit was booted with, alongside
+// the settings currently in effect
+func (d Dispatcher) SystemInfo(context.Context, *emptypb.Empty) (*dispatcher.SystemInfoMessage, error) {
+	return &dispatcher.SystemInfoMessage{
+		BootOptions: &dispatcher.BootOptions{
+			Target: bootOpts.Target,
+			Mask:   bootOpts.Mask,
+			Debug:  bootOpts.Debug,
+			SvcDir: bootOpts.SvcDir,
+		},
+		SvcDir: d.s.dir,
+		Target: d.s.Target(),
+		Debug:  sugar.Debug,
+	}, nil
+}
+
 func (d Dispatcher) SystemLogs(_ *emptypb.Empty, ds dispatcher.Dispatcher_SystemLogsServer) (err error) {
 	for i := maxLogLines - 1; i >= 0; i-- {
 		err = ds.Send(&dispatcher.LogMessage{Line: sugar.Buffer[i]})
@@ -28,15 +28,55 @@ type DispatcherClient interface {
 	Stop(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Status(ctx context.Context, in *Service, opts ...grpc.CallOption) (*ServiceStatus, error)
 	Reload(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Restart stops a service, waits for it to exit, and starts it
+	// again, without anything else being able to start or stop the
+	// service in between
+	Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RunNow runs a cron outside of its schedule, respecting its
+	// overlap policy
+	RunNow(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// History returns the past runs of a service, oldest first
+	History(ctx context.Context, in *Service, opts ...grpc.CallOption) (*HistoryMessage, error)
+	// ListServices returns each service in the order it would be
+	// booted, along with the group it's booted as part of
+	ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ServiceList, error)
+	// ShowConfig returns a service's config as vinit sees it, with
+	// defaults applied and the environment merged
+	ShowConfig(ctx context.Context, in *Service, opts ...grpc.CallOption) (*ServiceConfigMessage, error)
+	// ServiceLogs returns the most recent lines a service wrote to
+	// stdout and stderr
+	ServiceLogs(ctx context.Context, in *ServiceLogsRequest, opts ...grpc.CallOption) (*ServiceLogsMessage, error)
+	// Enable and Disable govern whether a service is started on
+	// boot, while a masked service can't be started at all
+	Enable(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Disable(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Mask(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Unmask(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// vinit related operations
 	ReadConfigs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	SystemStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Dispatcher_SystemStatusClient, error)
+	// SystemState returns the state of vinit itself, rather than of
+	// any one service
+	SystemState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SystemStateMessage, error)
 	Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionMessage, error)
+	SystemInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SystemInfoMessage, error)
 	SystemLogs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Dispatcher_SystemLogsClient, error)
 	// shutdown (etc.) commands
 	Shutdown(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Reboot(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Halt(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ScheduleShutdown and ScheduleReboot shut down or reboot at the
+	// time requested, or immediately, as Shutdown and Reboot do, where
+	// no time is given
+	ScheduleShutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ScheduleReboot(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CancelShutdown(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// KexecLoad loads a kernel to be booted into by a reboot
+	// in RebootMode KEXEC
+	KexecLoad(ctx context.Context, in *KexecRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Isolate switches to a target, stopping services outside of it
+	// and starting those within
+	Isolate(ctx context.Context, in *Target, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type dispatcherClient struct {
@@ -83,6 +123,96 @@ func (c *dispatcherClient) Reload(ctx context.Context, in *Service, opts ...grpc
 	return out, nil
 }
 
+func (c *dispatcherClient) Restart(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Restart", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) RunNow(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/RunNow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) History(ctx context.Context, in *Service, opts ...grpc.CallOption) (*HistoryMessage, error) {
+	out := new(HistoryMessage)
+	err := c.cc.Invoke(ctx, "/Dispatcher/History", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (*ServiceList, error) {
+	out := new(ServiceList)
+	err := c.cc.Invoke(ctx, "/Dispatcher/ListServices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) ShowConfig(ctx context.Context, in *Service, opts ...grpc.CallOption) (*ServiceConfigMessage, error) {
+	out := new(ServiceConfigMessage)
+	err := c.cc.Invoke(ctx, "/Dispatcher/ShowConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) ServiceLogs(ctx context.Context, in *ServiceLogsRequest, opts ...grpc.CallOption) (*ServiceLogsMessage, error) {
+	out := new(ServiceLogsMessage)
+	err := c.cc.Invoke(ctx, "/Dispatcher/ServiceLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) Enable(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Enable", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) Disable(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Disable", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) Mask(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Mask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) Unmask(ctx context.Context, in *Service, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Unmask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dispatcherClient) ReadConfigs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/Dispatcher/ReadConfigs", in, out, opts...)
@@ -124,6 +254,15 @@ func (x *dispatcherSystemStatusClient) Recv() (*ServiceStatus, error) {
 	return m, nil
 }
 
+func (c *dispatcherClient) SystemState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SystemStateMessage, error) {
+	out := new(SystemStateMessage)
+	err := c.cc.Invoke(ctx, "/Dispatcher/SystemState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dispatcherClient) Version(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VersionMessage, error) {
 	out := new(VersionMessage)
 	err := c.cc.Invoke(ctx, "/Dispatcher/Version", in, out, opts...)
@@ -133,6 +272,15 @@ func (c *dispatcherClient) Version(ctx context.Context, in *emptypb.Empty, opts
 	return out, nil
 }
 
+func (c *dispatcherClient) SystemInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SystemInfoMessage, error) {
+	out := new(SystemInfoMessage)
+	err := c.cc.Invoke(ctx, "/Dispatcher/SystemInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dispatcherClient) SystemLogs(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Dispatcher_SystemLogsClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Dispatcher_ServiceDesc.Streams[1], "/Dispatcher/SystemLogs", opts...)
 	if err != nil {
@@ -192,6 +340,51 @@ func (c *dispatcherClient) Halt(ctx context.Context, in *emptypb.Empty, opts ...
 	return out, nil
 }
 
+func (c *dispatcherClient) ScheduleShutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/ScheduleShutdown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) ScheduleReboot(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/ScheduleReboot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) CancelShutdown(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/CancelShutdown", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) KexecLoad(ctx context.Context, in *KexecRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/KexecLoad", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dispatcherClient) Isolate(ctx context.Context, in *Target, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/Dispatcher/Isolate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DispatcherServer is the server API for Dispatcher service.
 // All implementations must embed UnimplementedDispatcherServer
 // for forward compatibility
@@ -201,15 +394,55 @@ type DispatcherServer interface {
 	Stop(context.Context, *Service) (*emptypb.Empty, error)
 	Status(context.Context, *Service) (*ServiceStatus, error)
 	Reload(context.Context, *Service) (*emptypb.Empty, error)
+	// Restart stops a service, waits for it to exit, and starts it
+	// again, without anything else being able to start or stop the
+	// service in between
+	Restart(context.Context, *RestartRequest) (*emptypb.Empty, error)
+	// RunNow runs a cron outside of its schedule, respecting its
+	// overlap policy
+	RunNow(context.Context, *Service) (*emptypb.Empty, error)
+	// History returns the past runs of a service, oldest first
+	History(context.Context, *Service) (*HistoryMessage, error)
+	// ListServices returns each service in the order it would be
+	// booted, along with the group it's booted as part of
+	ListServices(context.Context, *ListServicesRequest) (*ServiceList, error)
+	// ShowConfig returns a service's config as vinit sees it, with
+	// defaults applied and the environment merged
+	ShowConfig(context.Context, *Service) (*ServiceConfigMessage, error)
+	// ServiceLogs returns the most recent lines a service wrote to
+	// stdout and stderr
+	ServiceLogs(context.Context, *ServiceLogsRequest) (*ServiceLogsMessage, error)
+	// Enable and Disable govern whether a service is started on
+	// boot, while a masked service can't be started at all
+	Enable(context.Context, *Service) (*emptypb.Empty, error)
+	Disable(context.Context, *Service) (*emptypb.Empty, error)
+	Mask(context.Context, *Service) (*emptypb.Empty, error)
+	Unmask(context.Context, *Service) (*emptypb.Empty, error)
 	// vinit related operations
 	ReadConfigs(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	SystemStatus(*emptypb.Empty, Dispatcher_SystemStatusServer) error
+	// SystemState returns the state of vinit itself, rather than of
+	// any one service
+	SystemState(context.Context, *emptypb.Empty) (*SystemStateMessage, error)
 	Version(context.Context, *emptypb.Empty) (*VersionMessage, error)
+	SystemInfo(context.Context, *emptypb.Empty) (*SystemInfoMessage, error)
 	SystemLogs(*emptypb.Empty, Dispatcher_SystemLogsServer) error
 	// shutdown (etc.) commands
 	Shutdown(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	Reboot(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
 	Halt(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// ScheduleShutdown and ScheduleReboot shut down or reboot at the
+	// time requested, or immediately, as Shutdown and Reboot do, where
+	// no time is given
+	ScheduleShutdown(context.Context, *ShutdownRequest) (*emptypb.Empty, error)
+	ScheduleReboot(context.Context, *ShutdownRequest) (*emptypb.Empty, error)
+	CancelShutdown(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	// KexecLoad loads a kernel to be booted into by a reboot
+	// in RebootMode KEXEC
+	KexecLoad(context.Context, *KexecRequest) (*emptypb.Empty, error)
+	// Isolate switches to a target, stopping services outside of it
+	// and starting those within
+	Isolate(context.Context, *Target) (*emptypb.Empty, error)
 	mustEmbedUnimplementedDispatcherServer()
 }
 
@@ -229,15 +462,51 @@ func (UnimplementedDispatcherServer) Status(context.Context, *Service) (*Service
 func (UnimplementedDispatcherServer) Reload(context.Context, *Service) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
 }
+func (UnimplementedDispatcherServer) Restart(context.Context, *RestartRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restart not implemented")
+}
+func (UnimplementedDispatcherServer) RunNow(context.Context, *Service) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunNow not implemented")
+}
+func (UnimplementedDispatcherServer) History(context.Context, *Service) (*HistoryMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedDispatcherServer) ListServices(context.Context, *ListServicesRequest) (*ServiceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
+func (UnimplementedDispatcherServer) ShowConfig(context.Context, *Service) (*ServiceConfigMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShowConfig not implemented")
+}
+func (UnimplementedDispatcherServer) ServiceLogs(context.Context, *ServiceLogsRequest) (*ServiceLogsMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ServiceLogs not implemented")
+}
+func (UnimplementedDispatcherServer) Enable(context.Context, *Service) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enable not implemented")
+}
+func (UnimplementedDispatcherServer) Disable(context.Context, *Service) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disable not implemented")
+}
+func (UnimplementedDispatcherServer) Mask(context.Context, *Service) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mask not implemented")
+}
+func (UnimplementedDispatcherServer) Unmask(context.Context, *Service) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unmask not implemented")
+}
 func (UnimplementedDispatcherServer) ReadConfigs(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReadConfigs not implemented")
 }
 func (UnimplementedDispatcherServer) SystemStatus(*emptypb.Empty, Dispatcher_SystemStatusServer) error {
 	return status.Errorf(codes.Unimplemented, "method SystemStatus not implemented")
 }
+func (UnimplementedDispatcherServer) SystemState(context.Context, *emptypb.Empty) (*SystemStateMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SystemState not implemented")
+}
 func (UnimplementedDispatcherServer) Version(context.Context, *emptypb.Empty) (*VersionMessage, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
 }
+func (UnimplementedDispatcherServer) SystemInfo(context.Context, *emptypb.Empty) (*SystemInfoMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SystemInfo not implemented")
+}
 func (UnimplementedDispatcherServer) SystemLogs(*emptypb.Empty, Dispatcher_SystemLogsServer) error {
 	return status.Errorf(codes.Unimplemented, "method SystemLogs not implemented")
 }
@@ -250,6 +519,21 @@ func (UnimplementedDispatcherServer) Reboot(context.Context, *emptypb.Empty) (*e
 func (UnimplementedDispatcherServer) Halt(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Halt not implemented")
 }
+func (UnimplementedDispatcherServer) ScheduleShutdown(context.Context, *ShutdownRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleShutdown not implemented")
+}
+func (UnimplementedDispatcherServer) ScheduleReboot(context.Context, *ShutdownRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleReboot not implemented")
+}
+func (UnimplementedDispatcherServer) CancelShutdown(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelShutdown not implemented")
+}
+func (UnimplementedDispatcherServer) KexecLoad(context.Context, *KexecRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KexecLoad not implemented")
+}
+func (UnimplementedDispatcherServer) Isolate(context.Context, *Target) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Isolate not implemented")
+}
 func (UnimplementedDispatcherServer) mustEmbedUnimplementedDispatcherServer() {}
 
 // UnsafeDispatcherServer may be embedded to opt out of forward compatibility for this service.
@@ -335,6 +619,186 @@ func _Dispatcher_Reload_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dispatcher_Restart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Restart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Restart",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Restart(ctx, req.(*RestartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_RunNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).RunNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/RunNow",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).RunNow(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/History",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).History(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_ListServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).ListServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/ListServices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).ListServices(ctx, req.(*ListServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_ShowConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).ShowConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/ShowConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).ShowConfig(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_ServiceLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).ServiceLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/ServiceLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).ServiceLogs(ctx, req.(*ServiceLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_Enable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Enable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Enable",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Enable(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_Disable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Disable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Disable",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Disable(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_Mask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Mask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Mask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Mask(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_Unmask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Service)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Unmask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Unmask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Unmask(ctx, req.(*Service))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Dispatcher_ReadConfigs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -374,6 +838,24 @@ func (x *dispatcherSystemStatusServer) Send(m *ServiceStatus) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Dispatcher_SystemState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).SystemState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/SystemState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).SystemState(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Dispatcher_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -392,6 +874,24 @@ func _Dispatcher_Version_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dispatcher_SystemInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).SystemInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/SystemInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).SystemInfo(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Dispatcher_SystemLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(emptypb.Empty)
 	if err := stream.RecvMsg(m); err != nil {
@@ -467,6 +967,96 @@ func _Dispatcher_Halt_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dispatcher_ScheduleShutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).ScheduleShutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/ScheduleShutdown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).ScheduleShutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_ScheduleReboot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).ScheduleReboot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/ScheduleReboot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).ScheduleReboot(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_CancelShutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).CancelShutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/CancelShutdown",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).CancelShutdown(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_KexecLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KexecRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).KexecLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/KexecLoad",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).KexecLoad(ctx, req.(*KexecRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dispatcher_Isolate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DispatcherServer).Isolate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Dispatcher/Isolate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DispatcherServer).Isolate(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Dispatcher_ServiceDesc is the grpc.ServiceDesc for Dispatcher service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -490,14 +1080,62 @@ var Dispatcher_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Reload",
 			Handler:    _Dispatcher_Reload_Handler,
 		},
+		{
+			MethodName: "Restart",
+			Handler:    _Dispatcher_Restart_Handler,
+		},
+		{
+			MethodName: "RunNow",
+			Handler:    _Dispatcher_RunNow_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _Dispatcher_History_Handler,
+		},
+		{
+			MethodName: "ListServices",
+			Handler:    _Dispatcher_ListServices_Handler,
+		},
+		{
+			MethodName: "ShowConfig",
+			Handler:    _Dispatcher_ShowConfig_Handler,
+		},
+		{
+			MethodName: "ServiceLogs",
+			Handler:    _Dispatcher_ServiceLogs_Handler,
+		},
+		{
+			MethodName: "Enable",
+			Handler:    _Dispatcher_Enable_Handler,
+		},
+		{
+			MethodName: "Disable",
+			Handler:    _Dispatcher_Disable_Handler,
+		},
+		{
+			MethodName: "Mask",
+			Handler:    _Dispatcher_Mask_Handler,
+		},
+		{
+			MethodName: "Unmask",
+			Handler:    _Dispatcher_Unmask_Handler,
+		},
 		{
 			MethodName: "ReadConfigs",
 			Handler:    _Dispatcher_ReadConfigs_Handler,
 		},
+		{
+			MethodName: "SystemState",
+			Handler:    _Dispatcher_SystemState_Handler,
+		},
 		{
 			MethodName: "Version",
 			Handler:    _Dispatcher_Version_Handler,
 		},
+		{
+			MethodName: "SystemInfo",
+			Handler:    _Dispatcher_SystemInfo_Handler,
+		},
 		{
 			MethodName: "Shutdown",
 			Handler:    _Dispatcher_Shutdown_Handler,
@@ -510,6 +1148,26 @@ var Dispatcher_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Halt",
 			Handler:    _Dispatcher_Halt_Handler,
 		},
+		{
+			MethodName: "ScheduleShutdown",
+			Handler:    _Dispatcher_ScheduleShutdown_Handler,
+		},
+		{
+			MethodName: "ScheduleReboot",
+			Handler:    _Dispatcher_ScheduleReboot_Handler,
+		},
+		{
+			MethodName: "CancelShutdown",
+			Handler:    _Dispatcher_CancelShutdown_Handler,
+		},
+		{
+			MethodName: "KexecLoad",
+			Handler:    _Dispatcher_KexecLoad_Handler,
+		},
+		{
+			MethodName: "Isolate",
+			Handler:    _Dispatcher_Isolate_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
@@ -377,7 +378,7 @@ func TestDispatcher_ReadConfigs(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 100)
 
-	currentStatus := d.s.services["app"].status
+	currentStatus := statusOf(d.s.services["app"])
 
 	_, err = d.ReadConfigs(context.Background(), new(emptypb.Empty))
 	if err == nil {
@@ -393,8 +394,19 @@ func TestDispatcher_ReadConfigs(t *testing.T) {
 		t.Fatalf("expected an error for service %q in %#v", "01-broken", cpe)
 	}
 
-	if !reflect.DeepEqual(currentStatus, d.s.services["app"].status) {
-		t.Errorf("expected %#v, received %#v", currentStatus, d.s.services["app"].status)
+	if !reflect.DeepEqual(currentStatus, statusOf(d.s.services["app"])) {
+		t.Errorf("expected %#v, received %#v", currentStatus, statusOf(d.s.services["app"]))
+	}
+
+	_, err = d.Stop(context.Background(), &dispatcher.Service{
+		Name: "app",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	if d.s.services["app"].isRunning() {
+		t.Error("expected reloaded service to be stopped, but it is still running")
 	}
 }
 
@@ -432,6 +444,34 @@ func TestDispatcher_SystemStatus(t *testing.T) {
 	}
 }
 
+func TestDispatcher_SystemState_BootFailure(t *testing.T) {
+	d := newDispatcher()
+	d.s.bootFailure = &BootFailure{
+		Group:   "system",
+		Service: "app",
+		Action:  FailureAction_Recovery,
+		Err:     fmt.Errorf("oh no"),
+	}
+
+	state, err := d.SystemState(context.Background(), new(emptypb.Empty))
+	if err != nil {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	bf := state.GetBootFailure()
+	if bf == nil {
+		t.Fatal("expected boot failure, received none")
+	}
+
+	if bf.Action != "recovery" {
+		t.Errorf("expected %q, received %q", "recovery", bf.Action)
+	}
+
+	if bf.Service != "app" {
+		t.Errorf("expected %q, received %q", "app", bf.Service)
+	}
+}
+
 func TestDispatcher_SystemLogs(t *testing.T) {
 	d := newDispatcher()
 
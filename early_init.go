@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	mounter   MountFunc    = unix.Mount
+	hostnamer HostnameFunc = unix.Sethostname
+	mknoder   MknodFunc    = unix.Mknod
+
+	mountsFile     = "/proc/self/mounts"
+	fstabFile      = "/etc/fstab"
+	hostnameFile   = "/etc/hostname"
+	randomSeedFile = "/var/lib/vinit/random-seed"
+	urandomFile    = "/dev/urandom"
+
+	// randomSeedSize is the number of bytes of seed to store for
+	// next boot; 512 is what most other inits use
+	randomSeedSize = 512
+)
+
+// MountFunc allows us to stub out mount calls in tests
+type MountFunc func(source, target, fstype string, flags uintptr, data string) error
+
+// HostnameFunc allows us to stub out setting the hostname in tests
+type HostnameFunc func([]byte) error
+
+// MknodFunc allows us to stub out creating device nodes in tests
+type MknodFunc func(path string, mode uint32, dev int) error
+
+// pseudoFS is a filesystem the kernel provides, rather than one
+// which lives on a disk
+type pseudoFS struct {
+	source string
+	target string
+	fstype string
+	flags  uintptr
+	data   string
+}
+
+var pseudoFilesystems = []pseudoFS{
+	{"proc", "/proc", "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+	{"sysfs", "/sys", "sysfs", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+	{"devtmpfs", "/dev", "devtmpfs", unix.MS_NOSUID, "mode=0755"},
+	{"devpts", "/dev/pts", "devpts", unix.MS_NOSUID | unix.MS_NOEXEC, "gid=5,mode=0620"},
+	{"shm", "/dev/shm", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=1777"},
+	{"run", "/run", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=0755"},
+}
+
+// EarlyInit configures the steps vinit takes to bring a system up
+// before any service is loaded.
+//
+// Every step is off by default, on the assumption that an initramfs
+// or a oneoff service handles these things
+type EarlyInit struct {
+	MountPseudoFS bool `toml:"mount_pseudo_fs"`
+	Kmsg          bool `toml:"kmsg"`
+	RemountRoot   bool `toml:"remount_root"`
+	Hostname      bool `toml:"hostname"`
+	SeedRNG       bool `toml:"seed_rng"`
+}
+
+type earlyInitStep struct {
+	name    string
+	enabled bool
+	f       func() error
+}
+
+// Run runs each enabled step in turn, stopping at the first error
+func (e EarlyInit) Run() (err error) {
+	for _, step := range []earlyInitStep{
+		{"mount_pseudo_fs", e.MountPseudoFS, mountPseudoFilesystems},
+		{"kmsg", e.Kmsg, ensureKmsg},
+		{"remount_root", e.RemountRoot, remountRoot},
+		{"hostname", e.Hostname, setHostname},
+		{"seed_rng", e.SeedRNG, seedRNG},
+	} {
+		if !step.enabled {
+			continue
+		}
+
+		err = step.f()
+		if err != nil {
+			return fmt.Errorf("early init step %s failed: %w", step.name, err)
+		}
+	}
+
+	return
+}
+
+// earlyInit loads vinit's config and runs the early init steps
+// configured there
+func earlyInit() (err error) {
+	c, err := LoadConfig(filepath.Join(svcDir, ".config.toml"))
+	if err != nil {
+		return
+	}
+
+	return c.Init.Run()
+}
+
+func mountPseudoFilesystems() (err error) {
+	for _, fs := range pseudoFilesystems {
+		err = mountPseudoFS(fs)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// mountProc mounts /proc, where it isn't already mounted, whether or
+// not early init is configured to. The kernel command line is read
+// from /proc, and may well say where config is to be loaded from
+func mountProc() error {
+	return mountPseudoFS(pseudoFilesystems[0])
+}
+
+func mountPseudoFS(fs pseudoFS) (err error) {
+	if isMounted(fs.target) {
+		return
+	}
+
+	err = os.MkdirAll(fs.target, 0755)
+	if err != nil {
+		return
+	}
+
+	err = mounter(fs.source, fs.target, fs.fstype, fs.flags, fs.data)
+	if err != nil {
+		return fmt.Errorf("mounting %s: %w", fs.target, err)
+	}
+
+	return
+}
+
+// isMounted returns true if something is mounted at target. Where
+// the mounts file can't be read (such as before /proc is mounted)
+// then we assume nothing is
+func isMounted(target string) bool {
+	f, err := os.Open(mountsFile) // #nosec: G304
+	if err != nil {
+		return false
+	}
+
+	defer f.Close() // #nosec: G307
+
+	entries, err := parseFstab(f)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if e.Target == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ensureKmsg creates /dev/kmsg, should it be missing, so that
+// vinit has somewhere to log to
+func ensureKmsg() (err error) {
+	_, err = os.Stat(kmesgF)
+	if !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// kmsg is the character device 1:11
+	return mknoder(kmesgF, unix.S_IFCHR|0644, int(unix.Mkdev(1, 11)))
+}
+
+// remountRoot remounts the root filesystem with the options from
+// fstab; usually this means read-write
+func remountRoot() (err error) {
+	f, err := os.Open(fstabFile) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	entries, err := parseFstab(f)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if e.Target != "/" {
+			continue
+		}
+
+		flags, data := e.mountFlags()
+
+		return mounter(e.Source, "/", e.FSType, flags|unix.MS_REMOUNT, data)
+	}
+
+	return fmt.Errorf("no entry for / in %s", fstabFile)
+}
+
+// setHostname sets the hostname from the first line of hostnameFile.
+//
+// A missing file isn't an error; it just means the kernel default
+// hostname is kept
+func setHostname() (err error) {
+	f, err := os.Open(hostnameFile) // #nosec: G304
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hostname := strings.TrimSpace(scanner.Text())
+		if hostname == "" || hostname[0] == '#' {
+			continue
+		}
+
+		return hostnamer([]byte(hostname))
+	}
+
+	return scanner.Err()
+}
+
+// seedRNG mixes the seed stored on the previous boot into the kernel's
+// entropy pool, and then stores a fresh seed for next time
+func seedRNG() (err error) {
+	seed, err := os.ReadFile(randomSeedFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	urandom, err := os.OpenFile(urandomFile, os.O_RDWR, 0) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer urandom.Close() // #nosec: G307
+
+	if len(seed) > 0 {
+		_, err = urandom.Write(seed)
+		if err != nil {
+			return
+		}
+	}
+
+	seed = make([]byte, randomSeedSize)
+
+	_, err = io.ReadFull(urandom, seed)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(randomSeedFile), 0700)
+	if err != nil {
+		return
+	}
+
+	return os.WriteFile(randomSeedFile, seed, 0600)
+}
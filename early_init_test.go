@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+type mountCall struct {
+	source string
+	target string
+	fstype string
+	flags  uintptr
+	data   string
+}
+
+type dummyMounter struct {
+	calls []mountCall
+}
+
+func (d *dummyMounter) Mount(source, target, fstype string, flags uintptr, data string) error {
+	d.calls = append(d.calls, mountCall{source, target, fstype, flags, data})
+
+	return nil
+}
+
+type dummyHostnamer struct {
+	hostname string
+}
+
+func (d *dummyHostnamer) Sethostname(p []byte) error {
+	d.hostname = string(p)
+
+	return nil
+}
+
+func TestEarlyInit_Run(t *testing.T) {
+	mountsFile = "testdata/early-init/mounts"
+	fstabFile = "testdata/early-init/fstab"
+	hostnameFile = "testdata/early-init/hostname"
+
+	for _, test := range []struct {
+		name           string
+		e              EarlyInit
+		expectMounts   []mountCall
+		expectHostname string
+	}{
+		{"nothing enabled", EarlyInit{}, nil, ""},
+		{"pseudo filesystems are only mounted when missing", EarlyInit{MountPseudoFS: true}, []mountCall{
+			{"run", "/run", "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=0755"},
+		}, ""},
+		{"root is remounted from fstab", EarlyInit{RemountRoot: true}, []mountCall{
+			{"/dev/sda2", "/", "ext4", unix.MS_NOATIME | unix.MS_REMOUNT, "errors=remount-ro"},
+		}, ""},
+		{"hostname is set", EarlyInit{Hostname: true}, nil, "mittens"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := new(dummyMounter)
+			mounter = m.Mount
+
+			h := new(dummyHostnamer)
+			hostnamer = h.Sethostname
+
+			err := test.e.Run()
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expectMounts, m.calls) {
+				t.Errorf("expected %#v, received %#v", test.expectMounts, m.calls)
+			}
+
+			if test.expectHostname != h.hostname {
+				t.Errorf("expected %q, received %q", test.expectHostname, h.hostname)
+			}
+		})
+	}
+}
+
+func TestMountProc(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		mounts       string
+		expectMounts []mountCall
+	}{
+		{"not yet mounted", "/this/path/does/not/exist/i/bloody/well/hope", []mountCall{
+			{"proc", "/proc", "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		}},
+		{"already mounted", "testdata/early-init/mounts", nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mountsFile = test.mounts
+
+			m := new(dummyMounter)
+			mounter = m.Mount
+
+			err := mountProc()
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expectMounts, m.calls) {
+				t.Errorf("expected %#v, received %#v", test.expectMounts, m.calls)
+			}
+		})
+	}
+}
+
+func TestEarlyInit_Run_Errors(t *testing.T) {
+	m := new(dummyMounter)
+	mounter = m.Mount
+
+	for _, test := range []struct {
+		name  string
+		fstab string
+	}{
+		{"missing fstab", "/this/path/does/not/exist/i/bloody/well/hope"},
+		{"fstab without root", "testdata/early-init/fstab-no-root"},
+		{"invalid fstab", "testdata/early-init/fstab-invalid"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fstabFile = test.fstab
+
+			err := EarlyInit{RemountRoot: true}.Run()
+			if err == nil {
+				t.Errorf("expected error, received none")
+			}
+		})
+	}
+}
+
+func TestSetHostname_MissingFile(t *testing.T) {
+	hostnameFile = "/this/path/does/not/exist/i/bloody/well/hope"
+
+	h := new(dummyHostnamer)
+	hostnamer = h.Sethostname
+
+	err := setHostname()
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if h.hostname != "" {
+		t.Errorf("expected hostname to be left alone, received %q", h.hostname)
+	}
+}
+
+func TestSeedRNG(t *testing.T) {
+	dir := t.TempDir()
+
+	// stand in for /dev/urandom with a file we can read back
+	urandomFile = filepath.Join(dir, "urandom")
+	randomSeedFile = filepath.Join(dir, "lib", "random-seed")
+
+	err := os.WriteFile(urandomFile, bytes.Repeat([]byte("a"), randomSeedSize*2), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(randomSeedFile), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldSeed := bytes.Repeat([]byte("b"), 16)
+
+	err = os.WriteFile(randomSeedFile, oldSeed, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = seedRNG()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	t.Run("old seed is written to the pool", func(t *testing.T) {
+		got, err := os.ReadFile(urandomFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasPrefix(got, oldSeed) {
+			t.Errorf("expected pool to start with %q, received %q", oldSeed, got[:len(oldSeed)])
+		}
+	})
+
+	t.Run("new seed is stored", func(t *testing.T) {
+		got, err := os.ReadFile(randomSeedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != randomSeedSize {
+			t.Errorf("expected %d bytes, received %d", randomSeedSize, len(got))
+		}
+	})
+}
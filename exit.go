@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// recordExit fills in how the process described by ps exited, and the
+// resources it used along the way.
+//
+// ps is nil when Wait fails, such as when the reaper collects the process
+// first; there's nothing to record then beyond the run having failed
+func (st *ServiceStatus) recordExit(ps *os.ProcessState) {
+	st.Signal = 0
+	st.CoreDumped = false
+
+	if ps == nil {
+		st.ExitStatus = -1
+
+		return
+	}
+
+	st.ExitStatus = ps.ExitCode()
+	st.UserTime = ps.UserTime()
+	st.SystemTime = ps.SystemTime()
+
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		st.Signal = ws.Signal()
+		st.CoreDumped = ws.CoreDump()
+	}
+
+	// linux reports ru_maxrss in kilobytes
+	if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+		st.MaxRSS = ru.Maxrss * 1024
+	}
+}
+
+// Signalled returns true when the process was killed by a signal,
+// rather than exiting
+func (st ServiceStatus) Signalled() bool {
+	return st.Signal != 0
+}
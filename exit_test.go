@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestServiceStatus_recordExit(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/exits")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := Dispatcher{s: s}
+
+	for _, test := range []struct {
+		name         string
+		expectStatus int32
+		expectSignal string
+	}{
+		{"segv-oneoff", -1, "SIGSEGV"},
+		{"clean-oneoff", 0, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s.Start(test.name, true) // #nosec: G104
+
+			status, err := d.Status(context.Background(), &dispatcher.Service{Name: test.name})
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			if test.expectStatus != status.ExitCode {
+				t.Errorf("expected %d, received %d", test.expectStatus, status.ExitCode)
+			}
+
+			if test.expectSignal != status.Signal {
+				t.Errorf("expected %q, received %q", test.expectSignal, status.Signal)
+			}
+
+			if status.MaxRss == 0 {
+				t.Error("expected max rss to be recorded")
+			}
+		})
+	}
+
+	t.Run("signalled runs fail", func(t *testing.T) {
+		st := statusOf(s.services["segv-oneoff"])
+
+		if st.Success {
+			t.Error("expected run to have failed")
+		}
+
+		if !st.Signalled() || st.Signal != syscall.SIGSEGV {
+			t.Errorf("expected %v, received %v", syscall.SIGSEGV, st.Signal)
+		}
+	})
+}
+
+func TestServiceStatus_recordExit_NoProcessState(t *testing.T) {
+	st := ServiceStatus{ExitStatus: 0, Signal: syscall.SIGTERM, CoreDumped: true}
+
+	st.recordExit(nil)
+
+	if st.ExitStatus != -1 {
+		t.Errorf("expected %d, received %d", -1, st.ExitStatus)
+	}
+
+	if st.Signalled() {
+		t.Errorf("expected no signal, received %v", st.Signal)
+	}
+
+	if st.CoreDumped {
+		t.Error("expected no core dump")
+	}
+}
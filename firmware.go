@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// efiGlobalVariable is the GUID under which OsIndications et al
+	// live, see: the UEFI spec, section 3.3
+	efiGlobalVariable = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+	// efiOsIndicationsBootToFWUI asks the firmware to stop at its
+	// setup screen on next boot
+	efiOsIndicationsBootToFWUI = uint64(0x01)
+
+	// efiVariableAttributes are non-volatile, boot service access,
+	// and runtime access
+	efiVariableAttributes = uint32(0x07)
+
+	// fsImmutableFlag comes from linux/fs.h, which x/sys doesn't export
+	fsImmutableFlag = 0x00000010
+)
+
+var (
+	firmwareSetter FirmwareSetupFunc = setFirmwareSetup
+
+	efiVarsDir = "/sys/firmware/efi/efivars"
+
+	errNoRebootArgument = status.Error(codes.InvalidArgument, "missing reboot argument")
+	errNoFirmwareSetup  = status.Error(codes.FailedPrecondition, "firmware does not support booting into setup")
+)
+
+// FirmwareSetupFunc allows us to stub out writing EFI variables in tests
+type FirmwareSetupFunc func() error
+
+// firmwareSetupSupported returns true when the firmware advertises
+// support for booting into its setup screen
+func firmwareSetupSupported() bool {
+	v, err := readEFIVar("OsIndicationsSupported")
+	if err != nil {
+		return false
+	}
+
+	return v&efiOsIndicationsBootToFWUI != 0
+}
+
+// setFirmwareSetup sets the EFI variable OsIndications so that the
+// next boot stops at the firmware setup screen
+func setFirmwareSetup() (err error) {
+	v, err := readEFIVar("OsIndications")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	return writeEFIVar("OsIndications", v|efiOsIndicationsBootToFWUI)
+}
+
+func efiVarPath(name string) string {
+	return filepath.Join(efiVarsDir, name+"-"+efiGlobalVariable)
+}
+
+// readEFIVar reads a 64 bit EFI variable. efivarfs prefixes the value
+// with 4 bytes of attributes
+func readEFIVar(name string) (v uint64, err error) {
+	b, err := os.ReadFile(efiVarPath(name))
+	if err != nil {
+		return
+	}
+
+	if len(b) < 4 {
+		return
+	}
+
+	// some firmware stores fewer than 8 bytes
+	value := make([]byte, 8)
+	copy(value, b[4:])
+
+	return binary.LittleEndian.Uint64(value), nil
+}
+
+// writeEFIVar writes a 64 bit EFI variable, first clearing the immutable
+// flag efivarfs sets on existing variables
+func writeEFIVar(name string, v uint64) (err error) {
+	fn := efiVarPath(name)
+
+	err = clearImmutable(fn)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	b := make([]byte, 12)
+	binary.LittleEndian.PutUint32(b, efiVariableAttributes)
+	binary.LittleEndian.PutUint64(b[4:], v)
+
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE, 0644) // #nosec: G302,G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	// efivarfs needs the whole variable in a single write
+	_, err = f.Write(b)
+
+	return
+}
+
+func clearImmutable(fn string) (err error) {
+	f, err := os.Open(fn) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	flags, err := unix.IoctlGetUint32(int(f.Fd()), unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		// not every filesystem supports flags; there's nothing
+		// to clear
+		return nil
+	}
+
+	if flags&fsImmutableFlag == 0 {
+		return
+	}
+
+	return unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, int(flags&^fsImmutableFlag))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+type dummyFirmwareSetter struct {
+	called bool
+}
+
+func (d *dummyFirmwareSetter) Set() error {
+	d.called = true
+
+	return nil
+}
+
+func TestSetFirmwareSetup(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		existing []byte
+		expect   uint64
+	}{
+		{"no existing variable", nil, efiOsIndicationsBootToFWUI},
+		{"existing indications are kept", []byte{0x07, 0, 0, 0, 0x04, 0, 0, 0, 0, 0, 0, 0}, efiOsIndicationsBootToFWUI | 0x04},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			efiVarsDir = t.TempDir()
+
+			if test.existing != nil {
+				err := os.WriteFile(efiVarPath("OsIndications"), test.existing, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := setFirmwareSetup()
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			b, err := os.ReadFile(efiVarPath("OsIndications"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(b) != 12 {
+				t.Fatalf("expected 12 bytes, received %d", len(b))
+			}
+
+			attrs := binary.LittleEndian.Uint32(b)
+			if attrs != efiVariableAttributes {
+				t.Errorf("expected %#x, received %#x", efiVariableAttributes, attrs)
+			}
+
+			got := binary.LittleEndian.Uint64(b[4:])
+			if test.expect != got {
+				t.Errorf("expected %#x, received %#x", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Reboot_Modes(t *testing.T) {
+	efiVarsDir = "testdata/efivars/supported"
+
+	for _, test := range []struct {
+		name           string
+		o              *dispatcher.RebootOptions
+		expectCmd      int
+		expectArg      string
+		expectFirmware bool
+	}{
+		{"restart2", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_RESTART2, Argument: "bootloader"}, restart2, "bootloader", false},
+		{"firmware", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_FIRMWARE}, restart, "", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := newDispatcher()
+
+			r := new(dummyRebooter)
+			rebooter = r.Reboot
+
+			f := new(dummyFirmwareSetter)
+			firmwareSetter = f.Set
+
+			_, err := d.ScheduleReboot(context.Background(), &dispatcher.ShutdownRequest{RebootOptions: test.o})
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if test.expectCmd != r.cmd {
+				t.Errorf("expected %X, received %X", test.expectCmd, r.cmd)
+			}
+
+			if test.expectArg != r.arg {
+				t.Errorf("expected %q, received %q", test.expectArg, r.arg)
+			}
+
+			if test.expectFirmware != f.called {
+				t.Errorf("expected %v, received %v", test.expectFirmware, f.called)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// FstabEntry is a single line from an fstab(5) formatted file, which
+// includes /proc/self/mounts
+type FstabEntry struct {
+	Source  string
+	Target  string
+	FSType  string
+	Options []string
+}
+
+var (
+	// mountOptions maps fstab options onto the mount flags they set
+	mountOptions = map[string]uintptr{
+		"ro":          unix.MS_RDONLY,
+		"nosuid":      unix.MS_NOSUID,
+		"nodev":       unix.MS_NODEV,
+		"noexec":      unix.MS_NOEXEC,
+		"sync":        unix.MS_SYNCHRONOUS,
+		"dirsync":     unix.MS_DIRSYNC,
+		"mand":        unix.MS_MANDLOCK,
+		"noatime":     unix.MS_NOATIME,
+		"nodiratime":  unix.MS_NODIRATIME,
+		"relatime":    unix.MS_RELATIME,
+		"strictatime": unix.MS_STRICTATIME,
+		"lazytime":    unix.MS_LAZYTIME,
+		"silent":      unix.MS_SILENT,
+	}
+
+	// ignoredMountOptions are options which mean something to fstab
+	// readers, or are the default anyway, and so mustn't be passed to
+	// the filesystem
+	ignoredMountOptions = map[string]bool{
+		"defaults": true,
+		"rw":       true,
+		"suid":     true,
+		"dev":      true,
+		"exec":     true,
+		"async":    true,
+		"atime":    true,
+		"auto":     true,
+		"noauto":   true,
+		"user":     true,
+		"nouser":   true,
+		"users":    true,
+		"nofail":   true,
+		"_netdev":  true,
+	}
+)
+
+// parseFstab parses the fstab(5) formatted data in r, ignoring
+// comments and blank lines
+func parseFstab(r io.Reader) (entries []FstabEntry, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid fstab line %q", line)
+		}
+
+		e := FstabEntry{
+			Source: unescapeFstab(fields[0]),
+			Target: unescapeFstab(fields[1]),
+			FSType: fields[2],
+		}
+
+		if len(fields) > 3 {
+			e.Options = strings.Split(fields[3], ",")
+		}
+
+		entries = append(entries, e)
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+// mountFlags turns e's options into the flags and data arguments
+// to mount(2)
+func (e FstabEntry) mountFlags() (flags uintptr, data string) {
+	extra := make([]string, 0)
+
+	for _, opt := range e.Options {
+		if f, ok := mountOptions[opt]; ok {
+			flags |= f
+
+			continue
+		}
+
+		if ignoredMountOptions[opt] || strings.HasPrefix(opt, "x-") {
+			continue
+		}
+
+		extra = append(extra, opt)
+	}
+
+	return flags, strings.Join(extra, ",")
+}
+
+// unescapeFstab turns the octal escapes fstab uses for whitespace
+// back into the characters they represent
+func unescapeFstab(s string) string {
+	return strings.NewReplacer(
+		`\040`, " ",
+		`\011`, "\t",
+		`\012`, "\n",
+		`\134`, `\`,
+	).Replace(s)
+}
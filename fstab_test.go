@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseFstab(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		fn          string
+		expect      []FstabEntry
+		expectError bool
+	}{
+		{"valid fstab", "testdata/early-init/fstab", []FstabEntry{
+			{"/dev/sda2", "/", "ext4", []string{"defaults", "noatime", "errors=remount-ro"}},
+			{"/dev/sda1", "/boot", "vfat", []string{"noauto", "x-systemd.automount"}},
+			{"/dev/sda3", "/srv/my data", "xfs", []string{"nodev", "nosuid"}},
+		}, false},
+		{"too few fields", "testdata/early-init/fstab-invalid", nil, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.Open(test.fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer f.Close()
+
+			got, err := parseFstab(f)
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestFstabEntry_mountFlags(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		options     []string
+		expectFlags uintptr
+		expectData  string
+	}{
+		{"no options", nil, 0, ""},
+		{"defaults", []string{"defaults"}, 0, ""},
+		{"read only", []string{"ro"}, unix.MS_RDONLY, ""},
+		{"flags and data", []string{"rw", "noatime", "nosuid", "errors=remount-ro", "commit=60"}, unix.MS_NOATIME | unix.MS_NOSUID, "errors=remount-ro,commit=60"},
+		{"fstab only options are dropped", []string{"noauto", "nofail", "x-systemd.automount"}, 0, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			flags, data := FstabEntry{Options: test.options}.mountFlags()
+			if test.expectFlags != flags {
+				t.Errorf("expected %#x, received %#x", test.expectFlags, flags)
+			}
+
+			if test.expectData != data {
+				t.Errorf("expected %q, received %q", test.expectData, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultBaud = 38400
+	defaultTerm = "linux"
+)
+
+var (
+	// gettyRespawnDelay is how long to wait between a getty exiting
+	// and being started again, to avoid thrashing a broken terminal
+	gettyRespawnDelay = time.Second
+
+	baudRates = map[int]uint32{
+		1200:   unix.B1200,
+		2400:   unix.B2400,
+		4800:   unix.B4800,
+		9600:   unix.B9600,
+		19200:  unix.B19200,
+		38400:  unix.B38400,
+		57600:  unix.B57600,
+		115200: unix.B115200,
+		230400: unix.B230400,
+		460800: unix.B460800,
+		921600: unix.B921600,
+	}
+)
+
+// ttyPath returns the full path to a tty, allowing for configs
+// which specify ttys as either "tty1" or "/dev/tty1"
+func ttyPath(tty string) string {
+	if filepath.IsAbs(tty) {
+		return tty
+	}
+
+	return filepath.Join("/dev", tty)
+}
+
+// openTTY opens a terminal for a getty, setting its baud rate
+func openTTY(tty string, baud int) (f *os.File, err error) {
+	f, err = os.OpenFile(ttyPath(tty), os.O_RDWR|unix.O_NOCTTY, 0) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close() // #nosec: G104
+		}
+	}()
+
+	fd := int(f.Fd())
+
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return
+	}
+
+	speed := baudRates[baud]
+
+	t.Cflag &^= unix.CBAUD
+	t.Cflag |= speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, t)
+
+	return
+}
+
+// gettyProcAttr returns the process attributes which make a tty
+// the controlling terminal of a new session for the process
+// started on it
+func gettyProcAttr(uid, gid uint32) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uid, Gid: gid},
+		Setsid:     true,
+		Setctty:    true,
+
+		// Ctty is the child's fd number for the tty; stdin
+		Ctty: 0,
+	}
+}
+
+// instances returns the services to supervise for s. For most services
+// this is just s, but gettys configured with more than one tty are split
+// into a service per tty, named for that tty
+func (s *Service) instances() []*Service {
+	if s.loadError != "" || s.Config.Type != ServiceType_Getty {
+		return []*Service{s}
+	}
+
+	ttys := s.Config.Getty.TTYs
+	if len(ttys) == 1 {
+		s.tty = ttys[0]
+
+		return []*Service{s}
+	}
+
+	out := make([]*Service, len(ttys))
+	for i, tty := range ttys {
+		svc := *s
+		svc.Name = s.Name + "@" + filepath.Base(tty)
+		svc.mu = new(sync.Mutex)
+		svc.runState = new(runState)
+		svc.tty = tty
+
+		out[i] = &svc
+	}
+
+	return out
+}
+
+// respawn runs a getty, starting it again each time it exits (such as
+// when somebody logs out) until stopped is closed
+func (s *Service) respawn(started, stopped chan struct{}) {
+	delay := gettyRespawnDelay
+
+	for {
+		// only the first run has anything waiting for it to start
+		s.start(started)
+		started = nil
+
+		if isClosed(stopped) {
+			return
+		}
+
+		sugar.Infow("getty exited, respawning",
+			"service", s.Name,
+			"tty", s.tty,
+		)
+
+		select {
+		case <-stopped:
+			return
+		case <-time.After(delay):
+		}
+
+		s.state.Lock()
+		if isClosed(stopped) {
+			s.state.Unlock()
+
+			return
+		}
+
+		s.status.Running = true
+		s.status.StartTime = time.Now()
+		s.state.Unlock()
+	}
+}
+
+// attachTTY opens the tty for a getty, and sets it as the stdin, stdout,
+// and stderr (and controlling terminal) of proc
+func (s *Service) attachTTY(proc *exec.Cmd) (tty *os.File, err error) {
+	tty, err = openTTY(s.tty, s.Config.Getty.Baud)
+	if err != nil {
+		return
+	}
+
+	proc.Stdin = tty
+	proc.Stdout = tty
+	proc.Stderr = tty
+	proc.SysProcAttr = gettyProcAttr(s.uid, s.gid)
+
+	proc.Env = make([]string, len(s.Env), len(s.Env)+1)
+	copy(proc.Env, s.Env)
+
+	if !hasEnv(proc.Env, "TERM") {
+		proc.Env = append(proc.Env, "TERM="+s.Config.Getty.Term)
+	}
+
+	return
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY returns the master side of a new pseudo-terminal, and the
+// path to its slave, for gettys to run on
+func openPTY(t *testing.T) (master *os.File, slave string) {
+	t.Helper()
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("cannot open pty: %v", err)
+	}
+
+	fd := int(master.Fd())
+
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func TestGetty_Config(t *testing.T) {
+	c, err := LoadServiceConfig("testdata/successing/getty.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &Getty{
+		TTYs: TTYs{"tty1", "ttyS0"},
+		Baud: 115200,
+		Term: "vt102",
+	}
+
+	if !reflect.DeepEqual(expect, c.Getty) {
+		t.Errorf("expected %#v, received %#v", expect, c.Getty)
+	}
+
+	c, err = LoadServiceConfig("testdata/mvs/getty.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect = &Getty{
+		TTYs: TTYs{"tty1"},
+		Baud: defaultBaud,
+		Term: defaultTerm,
+	}
+
+	if !reflect.DeepEqual(expect, c.Getty) {
+		t.Errorf("expected %#v, received %#v", expect, c.Getty)
+	}
+}
+
+func TestSupervisor_GettyInstances(t *testing.T) {
+	s, err := New("testdata/getty-services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string][]string{
+		"consoles": {"getty@tty1", "getty@tty2", "getty@ttyS0"},
+		"system":   {"app"},
+	}
+
+	if !reflect.DeepEqual(expect, s.groupsServices) {
+		t.Errorf("expected\n%#v\n\nreceived\n%#v", expect, s.groupsServices)
+	}
+
+	if s.services["getty@ttyS0"].tty != "/dev/ttyS0" {
+		t.Errorf("expected %q, received %q", "/dev/ttyS0", s.services["getty@ttyS0"].tty)
+	}
+}
+
+func TestService_Getty(t *testing.T) {
+	oldRespawnDelay := gettyRespawnDelay
+	defer func() {
+		gettyRespawnDelay = oldRespawnDelay
+	}()
+
+	gettyRespawnDelay = time.Millisecond * 50
+
+	master, slave := openPTY(t)
+	defer master.Close()
+
+	// hold the slave open so that reads from master don't fail
+	// between the getty exiting and being respawned
+	held, err := os.OpenFile(slave, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer held.Close()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadService("getty", pwd+"/testdata/getty-services/getty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.tty = slave
+
+	err = s.Start(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		s.state.Lock()
+		s.stopRespawning()
+		s.state.Unlock()
+	}()
+
+	// bin is a symlink to tty(1), which prints the terminal
+	// it's attached to, and we should see it more than once
+	// as the getty is respawned
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(master)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case line := <-lines:
+			if line != slave {
+				t.Errorf("expected %q, received %q", slave, line)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatalf("getty did not run on %s (run %d)", slave, i)
+		}
+	}
+}
+
+func TestService_Getty_RestartBetweenRespawns(t *testing.T) {
+	oldRespawnDelay := gettyRespawnDelay
+	defer func() {
+		gettyRespawnDelay = oldRespawnDelay
+	}()
+
+	gettyRespawnDelay = time.Millisecond * 200
+
+	master, slave := openPTY(t)
+	defer master.Close()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadService("getty", pwd+"/testdata/getty-services/getty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.tty = slave
+
+	// tty(1) exits straight away, leaving the getty waiting to be
+	// respawned
+	waitForRespawn := func() {
+		t.Helper()
+
+		for i := 0; s.isRunning(); i++ {
+			if i > 100 {
+				t.Fatal("getty did not exit")
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	err = s.Start(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRespawn()
+
+	err = s.Restart(RestartMode_Restart)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	waitForRespawn()
+
+	err = s.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	// neither the first respawn loop, nor the second, should run
+	// the getty again
+	time.Sleep(gettyRespawnDelay * 2)
+
+	if s.startCount() != 2 {
+		t.Errorf("expected %d, received %d", 2, s.startCount())
+	}
+}
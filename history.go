@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultHistoryLength is how many runs are kept per service
+	// when the system config doesn't say otherwise
+	defaultHistoryLength = 10
+
+	// historyFileSuffix follows the service name in the file a
+	// service's history is persisted to, within its directory
+	historyFileSuffix = ".history"
+
+	runExited        = "exited"
+	runStopped       = "stopped"
+	runTimedOut      = "timed out"
+	runFailedToStart = "failed to start"
+)
+
+// History configures how many past runs vinit keeps for each service
+type History struct {
+	// Length is how many runs are kept per service. Defaults
+	// to 10
+	Length int `toml:"length"`
+
+	// Persist writes each service's history to its directory, so
+	// that it survives reboots
+	Persist bool `toml:"persist"`
+}
+
+// Run describes a single run of a service's process
+type Run struct {
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	Pid        int       `json:"pid"`
+	ExitStatus int       `json:"exit_status"`
+
+	// Signal is the name of the signal which killed the run, if
+	// it was killed by one
+	Signal string `json:"signal,omitempty"`
+
+	// Reason is why the run ended
+	Reason string `json:"reason"`
+
+	// Restarts is how many times the service had been started
+	// before this run, since vinit started
+	Restarts int    `json:"restarts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// History returns the past runs of a service, oldest first
+func (d Dispatcher) History(_ context.Context, s *dispatcher.Service) (out *dispatcher.HistoryMessage, err error) {
+	out = new(dispatcher.HistoryMessage)
+
+	if s == nil || s.Name == "" {
+		return out, errNoService
+	}
+
+	history, err := d.s.History(s.Name)
+	if err != nil {
+		return
+	}
+
+	out.Runs = make([]*dispatcher.Run, len(history))
+	for i, r := range history {
+		out.Runs[i] = &dispatcher.Run{
+			StartTime:  timestamppb.New(r.StartTime),
+			EndTime:    timestamppb.New(r.EndTime),
+			Pid:        uint32(r.Pid),
+			ExitStatus: int32(r.ExitStatus),
+			Signal:     r.Signal,
+			Reason:     r.Reason,
+			Restarts:   uint32(r.Restarts),
+			Error:      r.Error,
+		}
+	}
+
+	return
+}
+
+func (s *Supervisor) History(name string) ([]Run, error) {
+	svc, ok := s.service(name)
+	if !ok {
+		return nil, errServiceNotExist
+	}
+
+	svc.state.Lock()
+	defer svc.state.Unlock()
+
+	return svc.history, nil
+}
+
+// addRun adds the run which just finished, with error err, to the
+// history of s.
+//
+// Callers must hold s.state
+func (s *Service) addRun(err error) {
+	r := Run{
+		StartTime:  s.status.StartTime,
+		EndTime:    s.status.EndTime,
+		Pid:        s.status.Pid,
+		ExitStatus: s.status.ExitStatus,
+		Restarts:   s.starts - 1,
+	}
+
+	if s.status.Signal != 0 {
+		r.Signal = unix.SignalName(s.status.Signal)
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	switch {
+	case s.status.TimedOut:
+		r.Reason = runTimedOut
+	case s.stopping:
+		r.Reason = runStopped
+	case s.status.Pid == 0:
+		r.Reason = runFailedToStart
+	default:
+		r.Reason = runExited
+	}
+
+	n := s.historyConf.Length
+	if n <= 0 {
+		n = defaultHistoryLength
+	}
+
+	// copy, rather than append in place, so that callers of
+	// Supervisor.History keep a consistent slice
+	history := append(s.history[:len(s.history):len(s.history)], r)
+	if len(history) > n {
+		history = history[len(history)-n:]
+	}
+
+	s.history = history
+
+	if !s.historyConf.Persist {
+		return
+	}
+
+	err = s.saveHistory()
+	if err != nil {
+		sugar.Warnw("could not persist service history",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+}
+
+// historyFile returns the file the history of s is persisted to.
+// Instances of a service share a directory, and so each has their
+// own file
+func (s *Service) historyFile() string {
+	return filepath.Join(s.dir, "."+s.Name+historyFileSuffix)
+}
+
+func (s *Service) saveHistory() (err error) {
+	b, err := json.Marshal(s.history)
+	if err != nil {
+		return
+	}
+
+	return os.WriteFile(s.historyFile(), b, 0600)
+}
+
+// loadHistory reads the persisted history of s, should there be one
+func (s *Service) loadHistory() (err error) {
+	b, err := os.ReadFile(s.historyFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return
+	}
+
+	return json.Unmarshal(b, &s.history)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestService_History(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/timeouts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	svc := s.services["hanging-oneoff"]
+	svc.dir = t.TempDir()
+	svc.historyConf = History{Length: 2, Persist: true}
+
+	for i := 0; i < 3; i++ {
+		s.Start("hanging-oneoff", true) // #nosec: G104
+	}
+
+	t.Run("history is bounded", func(t *testing.T) {
+		history, err := s.History("hanging-oneoff")
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		if len(history) != 2 {
+			t.Fatalf("expected 2 runs, received %d", len(history))
+		}
+
+		for i, r := range history {
+			if r.Restarts != i+1 {
+				t.Errorf("expected %d, received %d", i+1, r.Restarts)
+			}
+
+			if r.Reason != runTimedOut {
+				t.Errorf("expected %q, received %q", runTimedOut, r.Reason)
+			}
+
+			if r.Signal != "SIGTERM" {
+				t.Errorf("expected %q, received %q", "SIGTERM", r.Signal)
+			}
+
+			if r.Pid == 0 || r.StartTime.IsZero() || r.EndTime.IsZero() {
+				t.Errorf("expected run to be filled in, received %#v", r)
+			}
+		}
+	})
+
+	t.Run("history is persisted", func(t *testing.T) {
+		loaded := &Service{Name: svc.Name, dir: svc.dir, runState: new(runState)}
+
+		err := loaded.loadHistory()
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		expect := normaliseRuns(svc.history)
+		got := normaliseRuns(loaded.history)
+
+		if !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected %#v, received %#v", expect, got)
+		}
+	})
+}
+
+func TestService_History_Stopped(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	err = s.Start("base-app", false)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = s.Stop("base-app")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	history, err := s.History("base-app")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	if len(history) != 1 {
+		t.Fatalf("expected 1 run, received %d", len(history))
+	}
+
+	if history[0].Reason != runStopped {
+		t.Errorf("expected %q, received %q", runStopped, history[0].Reason)
+	}
+}
+
+func TestDispatcher_History(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		s           *dispatcher.Service
+		expectError error
+	}{
+		{"nil service", nil, errNoService},
+		{"empty service", &dispatcher.Service{}, errNoService},
+		{"unknown service", &dispatcher.Service{Name: "nonesuch"}, errServiceNotExist},
+		{"known service", &dispatcher.Service{Name: "app"}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.History(context.Background(), test.s)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+}
+
+// normaliseRuns strips what encoding runs loses; monotonic clock
+// readings and locations
+func normaliseRuns(runs []Run) (out []Run) {
+	out = make([]Run, len(runs))
+	for i, r := range runs {
+		r.StartTime = r.StartTime.Round(0).UTC()
+		r.EndTime = r.EndTime.Round(0).UTC()
+
+		out[i] = r
+	}
+
+	return
+}
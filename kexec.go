@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// kexec, from the same header as restart et al, reboots into the kernel
+// loaded by kexec_file_load(2)
+const kexec = 0x45584543
+
+var (
+	kexecLoader KexecLoadFunc = unix.KexecFileLoad
+
+	kexecLoadedFile = "/sys/kernel/kexec_loaded"
+	cmdlineFile     = "/proc/cmdline"
+
+	errNoKernel      = status.Error(codes.InvalidArgument, "missing kernel")
+	errNoKexecKernel = status.Error(codes.FailedPrecondition, "no kernel is loaded for kexec")
+)
+
+// KexecLoadFunc allows us to stub out kexec_file_load(2) in tests
+type KexecLoadFunc func(kernelFd int, initrdFd int, cmdline string, flags int) error
+
+// KexecLoad loads a kernel (and, optionally, an initrd) for a later
+// kexec reboot to boot into
+func (d Dispatcher) KexecLoad(_ context.Context, r *dispatcher.KexecRequest) (out *emptypb.Empty, err error) {
+	return new(emptypb.Empty), kexecLoad(r.GetKernel(), r.GetInitrd(), r.GetCmdline())
+}
+
+func kexecLoad(kernel, initrd, cmdline string) (err error) {
+	if kernel == "" {
+		return errNoKernel
+	}
+
+	k, err := os.Open(kernel) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer k.Close() // #nosec: G307
+
+	var (
+		initrdFd = -1
+		flags    int
+	)
+
+	if initrd == "" {
+		flags |= unix.KEXEC_FILE_NO_INITRAMFS
+	} else {
+		var i *os.File
+
+		i, err = os.Open(initrd) // #nosec: G304
+		if err != nil {
+			return
+		}
+
+		defer i.Close() // #nosec: G307
+
+		initrdFd = int(i.Fd())
+	}
+
+	if cmdline == "" {
+		var b []byte
+
+		b, err = os.ReadFile(cmdlineFile)
+		if err != nil {
+			return
+		}
+
+		cmdline = strings.TrimSpace(string(b))
+	}
+
+	err = kexecLoader(int(k.Fd()), initrdFd, cmdline, flags)
+	if err != nil {
+		return
+	}
+
+	sugar.Infow("loaded kernel for kexec",
+		"kernel", kernel,
+		"initrd", initrd,
+		"cmdline", cmdline,
+	)
+
+	return
+}
+
+// kexecLoaded returns true when the kernel has something loaded
+// to kexec into
+func kexecLoaded() bool {
+	b, err := os.ReadFile(kexecLoadedFile)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(b)) == "1"
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
+)
+
+type dummyKexecLoader struct {
+	called    bool
+	hasInitrd bool
+	cmdline   string
+	flags     int
+}
+
+func (d *dummyKexecLoader) Load(kernelFd, initrdFd int, cmdline string, flags int) error {
+	d.called = true
+	d.hasInitrd = initrdFd >= 0
+	d.cmdline = cmdline
+	d.flags = flags
+
+	return nil
+}
+
+func TestKexecLoad(t *testing.T) {
+	cmdlineFile = "testdata/kexec/cmdline"
+
+	for _, test := range []struct {
+		name          string
+		r             *dispatcher.KexecRequest
+		expectError   bool
+		expectInitrd  bool
+		expectCmdline string
+		expectFlags   int
+	}{
+		{"kernel, initrd, and cmdline", &dispatcher.KexecRequest{Kernel: "testdata/kexec/vmlinuz", Initrd: "testdata/kexec/initrd.img", Cmdline: "root=/dev/sdb1"}, false, true, "root=/dev/sdb1", 0},
+		{"cmdline is reused", &dispatcher.KexecRequest{Kernel: "testdata/kexec/vmlinuz", Initrd: "testdata/kexec/initrd.img"}, false, true, "BOOT_IMAGE=/vmlinuz root=/dev/sda2 ro quiet", 0},
+		{"no initrd", &dispatcher.KexecRequest{Kernel: "testdata/kexec/vmlinuz", Cmdline: "root=/dev/sdb1"}, false, false, "root=/dev/sdb1", unix.KEXEC_FILE_NO_INITRAMFS},
+		{"missing kernel", &dispatcher.KexecRequest{}, true, false, "", 0},
+		{"kernel does not exist", &dispatcher.KexecRequest{Kernel: "/this/path/does/not/exist/i/bloody/well/hope"}, true, false, "", 0},
+		{"initrd does not exist", &dispatcher.KexecRequest{Kernel: "testdata/kexec/vmlinuz", Initrd: "/this/path/does/not/exist/i/bloody/well/hope"}, true, false, "", 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			k := new(dummyKexecLoader)
+			kexecLoader = k.Load
+
+			_, err := Dispatcher{}.KexecLoad(context.Background(), test.r)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, received none")
+				}
+
+				if k.called {
+					t.Errorf("expected kernel not to be loaded")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			if test.expectInitrd != k.hasInitrd {
+				t.Errorf("expected %v, received %v", test.expectInitrd, k.hasInitrd)
+			}
+
+			if test.expectCmdline != k.cmdline {
+				t.Errorf("expected %q, received %q", test.expectCmdline, k.cmdline)
+			}
+
+			if test.expectFlags != k.flags {
+				t.Errorf("expected %#x, received %#x", test.expectFlags, k.flags)
+			}
+		})
+	}
+}
+
+func TestRebootAction(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		loaded      string
+		efiVars     string
+		o           *dispatcher.RebootOptions
+		expect      PowerAction
+		expectError error
+	}{
+		{"no options", "testdata/kexec/unloaded", "testdata/efivars/unsupported", nil, PowerAction{Cmd: restart}, nil},
+		{"restart", "testdata/kexec/unloaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_RESTART}, PowerAction{Cmd: restart}, nil},
+		{"kexec", "testdata/kexec/loaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_KEXEC}, PowerAction{Cmd: kexec}, nil},
+		{"kexec without a kernel loaded", "testdata/kexec/unloaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_KEXEC}, PowerAction{}, errNoKexecKernel},
+		{"kexec without kexec support", "/this/path/does/not/exist/i/bloody/well/hope", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_KEXEC}, PowerAction{}, errNoKexecKernel},
+		{"restart2", "testdata/kexec/unloaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_RESTART2, Argument: "recovery"}, PowerAction{Cmd: restart2, Arg: "recovery"}, nil},
+		{"restart2 without an argument", "testdata/kexec/unloaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_RESTART2}, PowerAction{}, errNoRebootArgument},
+		{"firmware", "testdata/kexec/unloaded", "testdata/efivars/supported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_FIRMWARE}, PowerAction{Cmd: restart, Firmware: true}, nil},
+		{"firmware without support", "testdata/kexec/unloaded", "testdata/efivars/unsupported", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_FIRMWARE}, PowerAction{}, errNoFirmwareSetup},
+		{"firmware without efi", "testdata/kexec/unloaded", "/this/path/does/not/exist/i/bloody/well/hope", &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_FIRMWARE}, PowerAction{}, errNoFirmwareSetup},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			kexecLoadedFile = test.loaded
+			efiVarsDir = test.efiVars
+
+			got, err := rebootAction(test.o)
+			if err != test.expectError {
+				t.Errorf("expected %v, received %v", test.expectError, err)
+			}
+
+			if test.expect != got {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Reboot_Kexec(t *testing.T) {
+	kexecLoadedFile = "testdata/kexec/loaded"
+
+	d := newDispatcher()
+
+	r := new(dummyRebooter)
+	rebooter = r.Reboot
+
+	_, err := d.ScheduleReboot(context.Background(), &dispatcher.ShutdownRequest{
+		RebootOptions: &dispatcher.RebootOptions{Mode: dispatcher.RebootMode_KEXEC},
+	})
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if r.cmd != kexec {
+		t.Errorf("expected %X, received %X", kexec, r.cmd)
+	}
+}
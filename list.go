@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInvalidServiceType = status.Error(codes.InvalidArgument, "invalid service type")
+
+// ListServices returns each service, ordered by group (in the order
+// groups are booted) and then by the order services start within their
+// group, optionally filtered by r
+func (d Dispatcher) ListServices(_ context.Context, r *dispatcher.ListServicesRequest) (out *dispatcher.ServiceList, err error) {
+	out = new(dispatcher.ServiceList)
+
+	var wantType *ServiceType
+	if t := r.GetType(); t != "" {
+		wantType = new(ServiceType)
+
+		if wantType.UnmarshalText([]byte(t)) != nil {
+			return out, errInvalidServiceType
+		}
+	}
+
+	listings := d.s.listServices()
+	out.Services = make([]*dispatcher.ServiceInfo, 0, len(listings))
+
+	for _, l := range listings {
+		svc := l.svc
+
+		switch {
+		case r.GetFailedOnly() && !svc.failed():
+			continue
+
+		case r.GetGroup() != "" && r.GetGroup() != l.group:
+			continue
+
+		case wantType != nil && (svc.loadError != "" || svc.Config.Type != *wantType):
+			continue
+		}
+
+		info := &dispatcher.ServiceInfo{
+			Name:          l.name,
+			Dir:           svc.dir,
+			Group:         l.group,
+			GroupPosition: int32(l.groupPosition),
+			LoadError:     svc.loadError,
+			Running:       svc.isRunning(),
+			Failed:        svc.failed(),
+		}
+
+		// a service we couldn't load has no meaningful type
+		if svc.loadError == "" {
+			info.Type = svc.Config.Type.String()
+		}
+
+		out.Services = append(out.Services, info)
+	}
+
+	return
+}
+
+// serviceListing places a service within the groups it's booted as
+// part of
+type serviceListing struct {
+	name  string
+	group string
+
+	// groupPosition is the index of group in Config.Groups, or -1
+	// for services in a group which isn't booted
+	groupPosition int
+
+	// svc is the service itself, as loaded alongside the listing
+	svc *Service
+}
+
+// listServices returns each service in the order it'd be booted in;
+// services in groups listed in Config.Groups come first, then any in
+// other groups by name, and finally any which failed to load
+func (s *Supervisor) listServices() (out []serviceListing) {
+	config, groupsServices, services := s.loaded()
+
+	groups := make([]string, 0, len(groupsServices))
+	for group := range groupsServices {
+		groups = append(groups, group)
+	}
+
+	position := func(group string) int {
+		for i, g := range config.Groups {
+			if g == group {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		pi, pj := position(groups[i]), position(groups[j])
+
+		switch {
+		case pi != pj && pi >= 0 && pj >= 0:
+			return pi < pj
+
+		case pi != pj:
+			// booted groups come before the rest
+			return pi >= 0
+
+		case groups[i] == "" || groups[j] == "":
+			// services which failed to load come last
+			return groups[j] == ""
+		}
+
+		return groups[i] < groups[j]
+	})
+
+	out = make([]serviceListing, 0, len(services))
+
+	for _, group := range groups {
+		for _, name := range groupsServices[group] {
+			out = append(out, serviceListing{
+				name:          name,
+				svc:           services[name],
+				group:         group,
+				groupPosition: position(group),
+			})
+		}
+	}
+
+	return
+}
+
+// failed returns true when s couldn't be loaded, or its last run
+// failed. A service stopped by hand hasn't failed
+func (s *Service) failed() bool {
+	if s.loadError != "" {
+		return true
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	return s.proc == nil && !s.status.StartTime.IsZero() && !s.status.Success && !s.stopping
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSupervisor_listServices(t *testing.T) {
+	s := &Supervisor{
+		Config: Config{Groups: []string{"network", "base"}},
+		groupsServices: map[string][]string{
+			"base":    {"udev", "syslog"},
+			"network": {"dhcpcd"},
+			"unused":  {"unused-app"},
+			"extra":   {"extra-app"},
+			"":        {"broken"},
+		},
+	}
+
+	expect := []serviceListing{
+		{"dhcpcd", "network", 0, nil},
+		{"udev", "base", 1, nil},
+		{"syslog", "base", 1, nil},
+		{"extra-app", "extra", -1, nil},
+		{"unused-app", "unused", -1, nil},
+		{"broken", "", -1, nil},
+	}
+
+	got := s.listServices()
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected %#v, received %#v", expect, got)
+	}
+}
+
+func TestDispatcher_ListServices(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		r           *dispatcher.ListServicesRequest
+		expect      []string
+		expectError error
+	}{
+		{"nil request lists everything", nil, []string{"app", "app-cronjob", "app-oneoff", "broken"}, nil},
+		{"empty request lists everything", &dispatcher.ListServicesRequest{}, []string{"app", "app-cronjob", "app-oneoff", "broken"}, nil},
+		{"failed services", &dispatcher.ListServicesRequest{FailedOnly: true}, []string{"broken"}, nil},
+		{"by type", &dispatcher.ListServicesRequest{Type: "cron"}, []string{"app-cronjob"}, nil},
+		{"by group", &dispatcher.ListServicesRequest{Group: "system"}, []string{"app", "app-cronjob", "app-oneoff"}, nil},
+		{"by unknown group", &dispatcher.ListServicesRequest{Group: "nonesuch"}, []string{}, nil},
+		{"by invalid type", &dispatcher.ListServicesRequest{Type: "nonesuch"}, []string{}, errInvalidServiceType},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := d.ListServices(context.Background(), test.r)
+			if test.expectError != err {
+				t.Fatalf("expected %#v, received %#v", test.expectError, err)
+			}
+
+			got := make([]string, len(l.Services))
+			for i, svc := range l.Services {
+				got[i] = svc.Name
+			}
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+
+	t.Run("services are described", func(t *testing.T) {
+		l, err := d.ListServices(context.Background(), &dispatcher.ListServicesRequest{Type: "oneoff"})
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		expect := &dispatcher.ServiceInfo{
+			Name:          "app-oneoff",
+			Dir:           d.s.services["app-oneoff"].dir,
+			Type:          "oneoff",
+			Group:         "system",
+			GroupPosition: 0,
+		}
+
+		if len(l.Services) != 1 || !proto.Equal(expect, l.Services[0]) {
+			t.Errorf("expected %v, received %v", expect, l.Services)
+		}
+	})
+}
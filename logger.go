@@ -18,19 +18,29 @@ var (
 type Logger struct {
 	Buffer []string
 
+	// Debug enables calls to Debugw, which are otherwise dropped
+	Debug bool
+
 	c chan string
 	f io.ReadWriter
 }
 
 func NewLogger(kmesgF string) (l Logger, err error) {
-	l.Buffer = make([]string, maxLogLines)
-
-	l.c = make(chan string)
-	l.f, err = os.OpenFile(kmesgF, os.O_RDWR|unix.O_CLOEXEC|unix.O_NONBLOCK|unix.O_NOCTTY, 0o666) // #nosec: G302,G304
+	f, err := os.OpenFile(kmesgF, os.O_RDWR|unix.O_CLOEXEC|unix.O_NONBLOCK|unix.O_NOCTTY, 0o666) // #nosec: G302,G304
 	if err != nil {
 		return
 	}
 
+	return newLogger(f), nil
+}
+
+// newLogger returns a started Logger which writes to f
+func newLogger(f io.ReadWriter) (l Logger) {
+	l.Buffer = make([]string, maxLogLines)
+
+	l.c = make(chan string)
+	l.f = f
+
 	go l.Start()
 
 	return
@@ -46,6 +56,14 @@ func (l *Logger) Start() {
 	}
 }
 
+func (l Logger) Debugw(msg string, kvs ...interface{}) {
+	if !l.Debug {
+		return
+	}
+
+	l.addLog("debug", msg, kvs...)
+}
+
 func (l Logger) Infow(msg string, kvs ...interface{}) {
 	l.addLog("info", msg, kvs...)
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware"
@@ -24,24 +23,113 @@ var (
 )
 
 func main() {
-	var err error
+	var err, initErr error
+
+	// Early init only makes sense when we're the init process; mounting
+	// over the /proc of a running system would be a bad time.
+	//
+	// /proc is mounted ahead of everything else, since the kernel
+	// command line, which is read from it, may change which services
+	// directory early init's config is loaded from
+	if os.Getpid() == 1 {
+		initErr = mountProc()
+	}
+
+	optsErr := loadBootOptions()
+
+	if os.Getpid() == 1 && initErr == nil {
+		initErr = earlyInit()
+	}
+
 	sugar, err = NewLogger(kmesgF)
 	if err != nil {
-		panic(err)
+		if initErr == nil {
+			panic(err)
+		}
+
+		// early init failing may well be why there's no kmsg; log
+		// to the console instead so we can still get to a recovery
+		// shell
+		sugar = newLogger(os.Stdout)
 	}
 
+	sugar.Debug = bootOpts.Debug
+
+	logBootOptions(optsErr)
+
 	go reap()
 
+	if os.Getpid() == 1 {
+		go handleSignals()
+	}
+
 	defer os.Remove(sockAddr)
 
+	if initErr != nil {
+		err = initErr
+	} else {
+		err = boot()
+	}
+
+	for {
+		sugar.Errorw("booting into recovery shell",
+			"error", err.Error(),
+		)
+
+		if recoveryShell() == RecoveryExit_Setup {
+			err = retryBoot()
+
+			continue
+		}
+
+		// Rebooting the machine vinit happens to be running on,
+		// when vinit isn't the init process, would be pretty rude
+		if os.Getpid() != 1 {
+			return
+		}
+
+		syncer()
+
+		err = rebooter(restart, "")
+		if err == nil {
+			return
+		}
+	}
+}
+
+// retryBoot boots vinit again, once the recovery shell exits. Anything
+// the previous attempt started is stopped first, rather than being
+// orphaned and started a second time, and early init is run again in
+// case whatever it failed on has since been fixed
+func retryBoot() (err error) {
+	if s := signalSupervisor.Swap(nil); s != nil {
+		err = s.StopAll()
+		if err != nil {
+			sugar.Warnw("could not stop services from previous boot",
+				"error", err.Error(),
+			)
+		}
+	}
+
+	if os.Getpid() == 1 {
+		err = earlyInit()
+		if err != nil {
+			return
+		}
+	}
+
+	return boot()
+}
+
+// boot sets vinit up, starting services, and then serves requests. It
+// only ever returns when something has gone wrong
+func boot() (err error) {
 	srv, err := Setup()
 	if err != nil {
-		sugar.Errorw("setup failed, booting into recovery shell",
+		sugar.Errorw("setup failed",
 			"error", err.Error(),
 		)
 
-		recoveryShell()
-
 		return
 	}
 
@@ -56,22 +144,24 @@ func main() {
 
 	lis, err := net.Listen("unix", sockAddr)
 	if err != nil {
-		sugar.Errorw("could not listen on socket address, booting into recovery shell",
+		sugar.Errorw("could not listen on socket address",
 			"sockAddr", sockAddr,
 			"error", err.Error(),
 		)
 
-		recoveryShell()
-
 		return
 	}
 
 	err = srv.Serve(lis)
+	if err == nil {
+		err = fmt.Errorf("server stopped")
+	}
+
 	sugar.Errorw("vinit failed",
 		"error", err.Error(),
 	)
 
-	recoveryShell()
+	return
 }
 
 func Setup() (grpcServer *grpc.Server, err error) {
@@ -90,12 +180,33 @@ func Setup() (grpcServer *grpc.Server, err error) {
 		err = nil
 	}
 
+	signalSupervisor.Store(supervisor)
+
+	supervisor.bootMasks = bootOpts.Mask
+
+	if bootOpts.Target != "" {
+		err = supervisor.setTarget(bootOpts.Target)
+		if err != nil {
+			sugar.Warnw("ignoring unknown boot target",
+				"target", bootOpts.Target,
+			)
+
+			err = nil
+		}
+	}
+
 	tlsCredentials, err := loadTLSCredentials()
 	if err != nil {
 		return
 	}
 
-	supervisor.StartAll()
+	err = supervisor.StartAll()
+	if err != nil {
+		err = handleBootFailure(supervisor, err.(BootFailure))
+		if err != nil {
+			return
+		}
+	}
 
 	d := Dispatcher{supervisor, dispatcher.UnimplementedDispatcherServer{}}
 
@@ -115,6 +226,31 @@ func Setup() (grpcServer *grpc.Server, err error) {
 	return
 }
 
+// handleBootFailure carries out the action configured for a critical
+// service failing to start, returning an error when vinit should
+// drop into the recovery shell
+func handleBootFailure(supervisor *Supervisor, bf BootFailure) (err error) {
+	switch bf.Action {
+	case FailureAction_Recovery:
+		return bf
+
+	case FailureAction_Reboot:
+		err = supervisor.shutdown(restart)
+		if err != nil {
+			return
+		}
+
+		// If we get here then the system hasn't rebooted, which
+		// is about as broken as things get
+		return bf
+	}
+
+	// FailureAction_Abort: we've stopped starting services, but
+	// there's no reason not to keep serving requests, so that folk
+	// can work out what's up
+	return nil
+}
+
 func envOrDefault(envvar, def string) string {
 	out, ok := os.LookupEnv(envvar)
 	if ok {
@@ -140,14 +276,3 @@ func loadTLSCredentials() (credentials.TransportCredentials, error) {
 
 	return credentials.NewTLS(config), nil
 }
-
-func recoveryShell() {
-	fmt.Println("Press Ctrl+D to reboot")
-
-	c := exec.Command("/sbin/agetty", "-L", "-8", "--autologin", "root", "115200", "tty1", "linux")
-	c.Stdin = os.Stdin
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-
-	_ = c.Run()
-}
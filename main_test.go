@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -10,6 +13,17 @@ func TestMain(m *testing.M) {
 
 	maxLogLines = 10
 
+	// don't write shutdown progress to the console of whichever
+	// machine runs the tests
+	consoleFile = os.DevNull
+
+	// nor broadcast shutdown messages to its users
+	utmpFile = os.DevNull
+
+	// nor signal every process on it, nor wait for them to exit
+	killer = func(int, syscall.Signal) error { return nil }
+	survivors = func() ([]int, error) { return []int{}, nil }
+
 	f, err := os.CreateTemp("", "")
 	if err != nil {
 		panic(err)
@@ -53,6 +67,86 @@ func TestSetup_MissingSvcDir(t *testing.T) {
 	}
 }
 
+func TestSetup_CriticalFailure(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svcDir = pwd + "/testdata/critical-services"
+
+	_, err = Setup()
+	if err == nil {
+		t.Fatal("expected error, received none")
+	}
+
+	if _, ok := err.(BootFailure); !ok {
+		t.Errorf("unexpected error of type: %T", err)
+	}
+}
+
+func TestHandleBootFailure(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		action      FailureAction
+		expectCmd   int
+		expectError bool
+	}{
+		{FailureAction_Abort, 0, false},
+		{FailureAction_Recovery, 0, true},
+		{FailureAction_Reboot, restart, true},
+	} {
+		t.Run(test.action.String(), func(t *testing.T) {
+			r := new(dummyRebooter)
+			rebooter = r.Reboot
+
+			s := new(dummySyncer)
+			syncer = s.Sync
+
+			err := handleBootFailure(d.s, BootFailure{Action: test.action, Err: fmt.Errorf("oh no")})
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if r.cmd != test.expectCmd {
+				t.Errorf("expected %X, received %X", test.expectCmd, r.cmd)
+			}
+		})
+	}
+}
+
+func TestRetryBoot(t *testing.T) {
+	d := newDispatcher()
+
+	err := d.s.Start("app", false)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	signalSupervisor.Store(d.s)
+
+	// fail the retry straight away, rather than serving requests
+	svcDir = "/tmp/this/dir/hopefully/doesnt/exist"
+
+	err = retryBoot()
+	if err == nil {
+		t.Errorf("expected error, received none")
+	}
+
+	if d.s.services["app"].isRunning() {
+		t.Error("services from the previous boot should have been stopped")
+	}
+
+	if signalSupervisor.Load() != nil {
+		t.Error("signals should no longer act upon the previous boot")
+	}
+}
+
 func TestSetup_MissingCerts(t *testing.T) {
 	svcDir = "testdata/services"
 	certDir = "/tmp/this/dir/hopefully/doesnt/exist"
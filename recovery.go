@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/GehirnInc/crypt"
+	_ "github.com/GehirnInc/crypt/md5_crypt"
+	_ "github.com/GehirnInc/crypt/sha256_crypt"
+	_ "github.com/GehirnInc/crypt/sha512_crypt"
+	"golang.org/x/sys/unix"
+)
+
+const maxPasswordAttempts = 3
+
+var (
+	shadowFile = "/etc/shadow"
+
+	// fallbackShells are tried, in order, when the configured recovery
+	// shell can't be started, or when vinit's own config can't be read
+	fallbackShells = []*StartupScript{
+		defaultStartupScript,
+		{cmd: "/sbin/sulogin"},
+		{cmd: "/bin/sh"},
+	}
+
+	errRootLocked   = errors.New("root account is locked")
+	errAuthAborted  = errors.New("authentication aborted")
+	errAuthExceeded = errors.New("too many incorrect passwords")
+)
+
+// console holds the files a recovery shell is attached to
+type console struct {
+	in  *os.File
+	out *os.File
+
+	// isTTY is true when in is a terminal, and so can be
+	// made the controlling terminal of the shell
+	isTTY bool
+}
+
+// openConsole opens the device at path for use by a recovery shell,
+// falling back to vinit's own stdin/ stdout when path is empty
+func openConsole(path string) (c console, err error) {
+	c.in, c.out = os.Stdin, os.Stdout
+
+	if path != "" {
+		var f *os.File
+
+		f, err = os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0) // #nosec: G304
+		if err != nil {
+			return
+		}
+
+		c.in, c.out = f, f
+	}
+
+	_, err = unix.IoctlGetTermios(int(c.in.Fd()), unix.TCGETS)
+	c.isTTY = err == nil
+	err = nil
+
+	return
+}
+
+func (c console) Close() error {
+	if c.in == os.Stdin {
+		return nil
+	}
+
+	return c.in.Close()
+}
+
+// recoveryShell drops into a shell, returning what vinit should do
+// once that shell exits
+func recoveryShell() RecoveryExit {
+	r := recoveryConfig()
+
+	c, err := openConsole(r.Console)
+	if err != nil {
+		sugar.Errorw("could not open console, falling back to stdin/ stdout",
+			"console", r.Console,
+			"error", err.Error(),
+		)
+
+		c, _ = openConsole("")
+	}
+
+	defer c.Close() // #nosec: G307
+
+	switch r.OnExit {
+	case RecoveryExit_Setup:
+		fmt.Fprintln(c.out, "Press Ctrl+D to retry booting")
+
+	default:
+		fmt.Fprintln(c.out, "Press Ctrl+D to reboot")
+	}
+
+	if r.Password {
+		err = authenticate(c, shadowFile)
+		if err != nil {
+			sugar.Errorw("recovery shell authentication failed",
+				"error", err.Error(),
+			)
+
+			return r.OnExit
+		}
+	}
+
+	err = runShell(c, shellCandidates(r))
+	if err != nil {
+		sugar.Errorw("could not start a recovery shell",
+			"error", err.Error(),
+		)
+	}
+
+	return r.OnExit
+}
+
+// recoveryConfig returns the recovery shell configuration from vinit's
+// config, returning an empty configuration (and so relying on fallback
+// shells) when that config can't be read
+func recoveryConfig() Recovery {
+	c, err := LoadConfig(filepath.Join(svcDir, ".config.toml"))
+	if err != nil {
+		sugar.Warnw("could not load config, using fallback recovery shells",
+			"error", err.Error(),
+		)
+
+		return Recovery{}
+	}
+
+	return c.Recovery
+}
+
+// shellCandidates returns the shells to try, in order, for r
+func shellCandidates(r Recovery) (candidates []*StartupScript) {
+	candidates = make([]*StartupScript, 0, len(fallbackShells)+1)
+
+	if r.Shell != nil {
+		candidates = append(candidates, r.Shell)
+	}
+
+	for _, s := range fallbackShells {
+		if r.Shell != nil && r.Shell.cmd == s.cmd {
+			continue
+		}
+
+		candidates = append(candidates, s)
+	}
+
+	return
+}
+
+// runShell runs the first of candidates which can be started, attached
+// to c, returning once it exits
+func runShell(c console, candidates []*StartupScript) (err error) {
+	for _, s := range candidates {
+		cmd := exec.Command(s.cmd, s.args...) // #nosec G204
+		cmd.Stdin = c.in
+		cmd.Stdout = c.out
+		cmd.Stderr = c.out
+
+		if c.isTTY {
+			cmd.SysProcAttr = &syscall.SysProcAttr{
+				Setsid:  true,
+				Setctty: true,
+				Ctty:    0,
+			}
+		}
+
+		err = cmd.Start()
+		if err != nil {
+			sugar.Warnw("could not start recovery shell, trying the next one",
+				"shell", s.cmd,
+				"error", err.Error(),
+			)
+
+			continue
+		}
+
+		// The shell exiting non-zero is entirely normal (the last
+		// command run in it failing, say) and so isn't an error
+		_ = cmd.Wait()
+
+		return nil
+	}
+
+	return
+}
+
+// authenticate prompts for the root password on c, in the style of
+// sulogin, returning nil if the password matches the one in shadow
+func authenticate(c console, shadow string) (err error) {
+	hash, err := rootPasswordHash(shadow)
+	if err != nil {
+		return
+	}
+
+	// no password is set; there's nothing to check against
+	if hash == "" {
+		return
+	}
+
+	if strings.HasPrefix(hash, "!") || hash == "*" {
+		return errRootLocked
+	}
+
+	if !crypt.IsHashSupported(hash) {
+		return fmt.Errorf("unsupported password hash for root")
+	}
+
+	crypter := crypt.NewFromHash(hash)
+	scanner := bufio.NewScanner(c.in)
+
+	for i := 0; i < maxPasswordAttempts; i++ {
+		fmt.Fprint(c.out, "Give root password for maintenance\n(or press Control-D to continue): ")
+
+		password, ok := readPassword(c, scanner)
+		fmt.Fprintln(c.out)
+
+		if !ok {
+			return errAuthAborted
+		}
+
+		if crypter.Verify(hash, []byte(password)) == nil {
+			return nil
+		}
+
+		fmt.Fprintln(c.out, "Login incorrect")
+	}
+
+	return errAuthExceeded
+}
+
+// readPassword reads a line from scanner, turning off echo on c
+// where c is a terminal. ok is false on EOF (such as Ctrl+D)
+func readPassword(c console, scanner *bufio.Scanner) (password string, ok bool) {
+	if c.isTTY {
+		fd := int(c.in.Fd())
+
+		old, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err == nil {
+			noEcho := *old
+			noEcho.Lflag &^= unix.ECHO
+
+			if unix.IoctlSetTermios(fd, unix.TCSETS, &noEcho) == nil {
+				defer unix.IoctlSetTermios(fd, unix.TCSETS, old) // #nosec: G104
+			}
+		}
+	}
+
+	if !scanner.Scan() {
+		return
+	}
+
+	return scanner.Text(), true
+}
+
+// rootPasswordHash returns the password hash for root from the shadow
+// file at fn
+func rootPasswordHash(fn string) (hash string, err error) {
+	f, err := os.Open(fn) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	return shadowHash(f, "root")
+}
+
+func shadowHash(r io.Reader, user string) (hash string, err error) {
+	var fields []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields = strings.Split(scanner.Text(), ":")
+		if len(fields) < 2 || fields[0] != user {
+			continue
+		}
+
+		return fields[1], nil
+	}
+
+	err = scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("user %s does not exist", user)
+	}
+
+	return
+}
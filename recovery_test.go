@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestShellCandidates(t *testing.T) {
+	sh := &StartupScript{cmd: "/bin/sh", args: []string{"-l"}}
+	custom := &StartupScript{cmd: "/bin/bash"}
+
+	for _, test := range []struct {
+		name   string
+		r      Recovery
+		expect []*StartupScript
+	}{
+		{"no shell configured uses fallbacks", Recovery{}, fallbackShells},
+		{"configured shell goes first", Recovery{Shell: custom}, append([]*StartupScript{custom}, fallbackShells...)},
+		{"configured shell is not duplicated", Recovery{Shell: sh}, []*StartupScript{sh, fallbackShells[0], fallbackShells[1]}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := shellCandidates(test.r)
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestRunShell(t *testing.T) {
+	c := console{in: os.Stdin, out: os.Stdout}
+	missing := &StartupScript{cmd: "/this/path/does/not/exist/i/bloody/well/hope"}
+
+	for _, test := range []struct {
+		name        string
+		candidates  []*StartupScript
+		expectError bool
+	}{
+		{"first shell runs", []*StartupScript{{cmd: "/usr/bin/true"}}, false},
+		{"falls through to working shell", []*StartupScript{missing, {cmd: "/usr/bin/true"}}, false},
+		{"shell exiting non-zero is fine", []*StartupScript{{cmd: "/usr/bin/false"}}, false},
+		{"no working shells", []*StartupScript{missing}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := runShell(c, test.candidates)
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		shadow      string
+		input       string
+		expectError error
+	}{
+		{"correct password", "testdata/shadow/valid", "hunter2\n", nil},
+		{"correct password on second attempt", "testdata/shadow/valid", "letmein\nhunter2\n", nil},
+		{"too many incorrect passwords", "testdata/shadow/valid", "a\nb\nc\nhunter2\n", errAuthExceeded},
+		{"ctrl+d aborts", "testdata/shadow/valid", "", errAuthAborted},
+		{"locked root", "testdata/shadow/locked", "hunter2\n", errRootLocked},
+		{"empty password needs no prompt", "testdata/shadow/empty", "", nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			in, err := os.CreateTemp("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.Remove(in.Name())
+			defer in.Close()
+
+			_, err = in.WriteString(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = in.Seek(0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := os.CreateTemp("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.Remove(out.Name())
+			defer out.Close()
+
+			err = authenticate(console{in: in, out: out}, test.shadow)
+			if err != test.expectError {
+				t.Errorf("expected %v, received %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_MissingShadow(t *testing.T) {
+	err := authenticate(console{in: os.Stdin, out: os.Stdout}, "/this/path/does/not/exist/i/bloody/well/hope")
+	if err == nil {
+		t.Errorf("expected error, received none")
+	}
+}
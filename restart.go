@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	RestartMode_Restart RestartMode = iota
+	RestartMode_TryRestart
+	RestartMode_ReloadOrRestart
+)
+
+var errInvalidRestartMode = status.Error(codes.InvalidArgument, "invalid restart mode")
+
+// RestartMode governs how a service is restarted; namely:
+//
+//  1. RestartMode_Restart stops the service, if running, and starts it again
+//  2. RestartMode_TryRestart restarts the service only if it's already running
+//  3. RestartMode_ReloadOrRestart reloads the service if it's running, and starts it otherwise
+type RestartMode int8
+
+// Restart restarts a service on the server side, so that the service
+// can't be started by anything else between being stopped and started
+func (d Dispatcher) Restart(_ context.Context, r *dispatcher.RestartRequest) (out *emptypb.Empty, err error) {
+	out = new(emptypb.Empty)
+
+	if r.GetService().GetName() == "" {
+		return out, errNoService
+	}
+
+	var mode RestartMode
+
+	switch r.GetMode() {
+	case dispatcher.RestartMode_RESTART_ALWAYS:
+		mode = RestartMode_Restart
+
+	case dispatcher.RestartMode_TRY_RESTART:
+		mode = RestartMode_TryRestart
+
+	case dispatcher.RestartMode_RELOAD_OR_RESTART:
+		mode = RestartMode_ReloadOrRestart
+
+	default:
+		return out, errInvalidRestartMode
+	}
+
+	return out, d.s.Restart(r.Service.Name, mode)
+}
+
+func (s *Supervisor) Restart(name string, mode RestartMode) error {
+	svc, ok := s.service(name)
+	if !ok {
+		return errServiceNotExist
+	}
+
+	if svc.loadError != "" {
+		return errServiceDodgyConf
+	}
+
+	if s.masked(name) {
+		return errServiceMasked
+	}
+
+	return svc.Restart(mode)
+}
+
+// Restart restarts s according to mode, waiting for s to exit fully
+// before starting it again. s.mu is held throughout
+func (s *Service) Restart(mode RestartMode) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := s.isRunning()
+
+	switch {
+	case mode == RestartMode_TryRestart && !running:
+		return
+
+	case mode == RestartMode_ReloadOrRestart && running:
+		return s.reload()
+	}
+
+	if running {
+		err = s.stop(context.Background())
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = s.launch()
+
+	return
+}
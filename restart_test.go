@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestService_Restart(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	svc := s.services["base-app"]
+
+	for _, test := range []struct {
+		name          string
+		mode          RestartMode
+		expectRunning bool
+		expectNewPid  bool
+	}{
+		{"try-restart leaves stopped services alone", RestartMode_TryRestart, false, false},
+		{"reload-or-restart starts stopped services", RestartMode_ReloadOrRestart, true, true},
+		{"restart restarts running services", RestartMode_Restart, true, true},
+		{"try-restart restarts running services", RestartMode_TryRestart, true, true},
+		{"reload-or-restart reloads running services", RestartMode_ReloadOrRestart, true, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pid := statusOf(svc).Pid
+
+			err := s.Restart("base-app", test.mode)
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			if test.expectRunning != svc.isRunning() {
+				t.Errorf("expected running to be %v", test.expectRunning)
+			}
+
+			if test.expectNewPid == (pid == statusOf(svc).Pid) {
+				t.Errorf("expected new pid to be %v (old: %d, new: %d)", test.expectNewPid, pid, statusOf(svc).Pid)
+			}
+		})
+	}
+}
+
+func TestService_Restart_Concurrent(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 5)
+	)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs <- s.Restart("extra-app", RestartMode_Restart)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error %#v", err)
+		}
+	}
+
+	if !s.services["extra-app"].isRunning() {
+		t.Error("service extra-app should be running")
+	}
+}
+
+func TestDispatcher_Restart(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		r           *dispatcher.RestartRequest
+		expectError error
+	}{
+		{"nil request", nil, errNoService},
+		{"missing service", &dispatcher.RestartRequest{}, errNoService},
+		{"unknown service", &dispatcher.RestartRequest{Service: &dispatcher.Service{Name: "nonesuch"}}, errServiceNotExist},
+		{"dodgy service", &dispatcher.RestartRequest{Service: &dispatcher.Service{Name: "broken"}}, errServiceDodgyConf},
+		{"invalid mode", &dispatcher.RestartRequest{Service: &dispatcher.Service{Name: "app"}, Mode: 100}, errInvalidRestartMode},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.Restart(context.Background(), test.r)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 type ServiceStatus struct {
@@ -18,6 +23,21 @@ type ServiceStatus struct {
 	EndTime    time.Time
 	Success    bool
 	Error      error
+
+	// TimedOut is set when a run outlives its service's Timeout,
+	// and was stopped
+	TimedOut bool
+
+	// Signal is the signal which killed the process, if any, and
+	// CoreDumped is set where doing so dumped core
+	Signal     syscall.Signal
+	CoreDumped bool
+
+	// UserTime, SystemTime, and MaxRSS (in bytes) are the resources
+	// the process used, as reported by wait4(2)
+	UserTime   time.Duration
+	SystemTime time.Duration
+	MaxRSS     int64
 }
 
 type Service struct {
@@ -27,15 +47,29 @@ type Service struct {
 
 	// The following only need to be set once, no matter
 	// how often a service is restarted
+	dir    string
 	uid    uint32
 	gid    uint32
 	bin    string
 	wd     string
 	logdir string
 
-	// The following get set on Service.Start()
-	status ServiceStatus
-	proc   *exec.Cmd
+	// tty is the terminal a getty runs on
+	tty string
+
+	// mu is held while s is being started, stopped, reloaded,
+	// or restarted, so that these don't race one another
+	mu *sync.Mutex
+
+	// runState is what s is doing, and has done, which outlives any
+	// one load of its config
+	*runState
+
+	// listeners are the sockets vinit holds for the service
+	listeners []*os.File
+
+	// historyConf bounds, and decides whether to persist, history
+	historyConf History
 
 	// loadError is set if a call to Supervisor.LoadConfigs
 	// fails and so new config hasn't been picked up.
@@ -46,10 +80,66 @@ type Service struct {
 	loadError string
 }
 
+// runState is the state of a service which changes as its process
+// starts and exits, or as it's armed, triggered, and scheduled.
+//
+// Reloading configs replaces a Service, but not its runState, so that
+// running services aren't lost track of; see: Supervisor.LoadConfigs
+type runState struct {
+	// state guards the following, which are read and written from
+	// several goroutines. Unlike Service.mu it's only ever held
+	// briefly
+	state sync.Mutex
+
+	// The following get set on Service.Start()
+	status ServiceStatus
+	proc   *exec.Cmd
+
+	// done is closed once proc exits
+	done chan struct{}
+
+	// stopping is set by Service.Stop, until the service is
+	// next started
+	stopping bool
+
+	// respawning is closed to stop a getty's respawn loop, and is nil
+	// while there's no loop to stop. Each loop has a channel of its
+	// own, so that a loop which has yet to notice it was stopped
+	// doesn't carry on once the getty is started again
+	respawning chan struct{}
+
+	// disarmed is closed to stop the watchers which start an on
+	// demand service, and is nil while it isn't armed, see: Service.arm
+	disarmed chan struct{}
+
+	// triggeredAt and triggeredBy are set each time one of the
+	// service's triggers fires
+	triggeredAt time.Time
+	triggeredBy string
+
+	// queued is set when a cron is due to run while already
+	// running, and its overlap policy is OverlapPolicy_Queue
+	queued bool
+
+	// nextRun is when a cron is next due to run, and ranOnBoot is
+	// set once an @reboot cron has run
+	nextRun   time.Time
+	ranOnBoot bool
+
+	// history holds past runs, oldest first, bounded by
+	// Service.historyConf. starts counts how often the service has
+	// been started since vinit started
+	history []Run
+	starts  int
+}
+
 func LoadService(name, dir string) (s *Service, err error) {
 	s = new(Service)
 
 	s.Name = name
+	s.dir = dir
+	s.mu = new(sync.Mutex)
+	s.runState = new(runState)
 	s.Config, err = LoadServiceConfig(filepath.Join(dir, ".config.toml"))
 	if err != nil {
 		return
@@ -98,70 +188,134 @@ func LoadService(name, dir string) (s *Service, err error) {
 	return
 }
 
+// Start starts s. Where wait is true, and s is a oneoff, Start only
+// returns once s has completed
 func (s *Service) Start(wait bool) (err error) {
+	s.mu.Lock()
+	result, err := s.launch()
+	s.mu.Unlock()
+
+	if err != nil || !wait || s.Config.Type != ServiceType_Oneoff {
+		return
+	}
+
+	return <-result
+}
+
+// launch starts s, returning once its process has been started (or
+// has failed to start). The returned channel receives the error s
+// completes with, for services other than gettys, which respawn.
+//
+// Callers must hold s.mu
+func (s *Service) launch() (result chan error, err error) {
 	if s.isRunning() {
-		return fmt.Errorf("service is already running")
+		return nil, fmt.Errorf("service is already running")
 	}
 
-	defer func() {
-		s.proc = nil
-	}()
+	// on demand services are started without going through
+	// Supervisor.Start, and so check for themselves
+	if s.isMasked() {
+		return nil, errServiceMasked
+	}
+
+	err = s.listen()
+	if err != nil {
+		return
+	}
 
-	s.status.Running = true
+	var respawning chan struct{}
+	if s.Config.Type == ServiceType_Getty {
+		respawning = make(chan struct{})
+	}
+
+	s.state.Lock()
 	s.status = ServiceStatus{
+		Running:   true,
 		StartTime: time.Now(),
 	}
+	s.stopping = false
 
-	if s.Config.Type == ServiceType_Oneoff && wait {
-		s.status.Error = s.start()
-		s.status.Success = s.Config.Oneoff.Success(s.status.ExitStatus)
+	// a getty started again between respawns would otherwise
+	// have two loops respawning it
+	s.stopRespawning()
+	s.respawning = respawning
+	s.state.Unlock()
+
+	started := make(chan struct{})
+
+	if respawning != nil {
+		go s.respawn(started, respawning)
+		<-started
 
-		return s.status.Error
+		return
 	}
 
+	result = make(chan error, 1)
+
 	go func() {
-		s.status.Running = true
+		result <- s.start(started)
+	}()
 
-		s.status.Error = s.start()
+	<-started
 
-		switch s.Config.Type {
-		case ServiceType_Service:
-			// if we get here, the service has failed
-			s.status.Success = false
+	return
+}
 
-		case ServiceType_Cron:
-			// crons are errors unless they exit 0
-			s.status.Success = s.status.ExitStatus == 0
+func (s *Service) start(started chan struct{}) (err error) {
+	done := make(chan struct{})
+	defer close(done)
 
-		case ServiceType_Oneoff:
-			// oneoffs have a list of valid exits
-			s.status.Success = s.Config.Oneoff.Success(s.status.ExitStatus)
+	// let launch know we're done starting, however that went
+	var once sync.Once
+	signalStarted := func() {
+		if started != nil {
+			once.Do(func() { close(started) })
 		}
+	}
 
-		if !s.status.Success {
-			sugar.Errorw("service finishes unexpectedly",
-				"status", s.status.ExitStatus,
-				"service", s.Name,
-				"error", s.status.Error.Error(),
-			)
-		}
+	defer signalStarted()
+
+	s.state.Lock()
+	s.starts++
+	s.state.Unlock()
+
+	// record how the run went before done is closed, so that
+	// anything waiting on done sees the run's final status
+	defer func() {
+		s.state.Lock()
+		defer s.state.Unlock()
+
+		s.finish(err)
 	}()
 
-	return nil
-}
+	proc := exec.Command(s.bin, s.Config.Command.Args...) // #nosec G204
+	proc.Env = s.Env
+	proc.Dir = s.wd
+	proc.SysProcAttr = &syscall.SysProcAttr{}
+	proc.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(s.uid), Gid: uint32(s.gid)}
+
+	// a run which times out is stopped along with anything it
+	// started, and so needs a process group of its own
+	if s.Config.Timeout > 0 {
+		proc.SysProcAttr.Setpgid = true
+	}
 
-func (s *Service) start() (err error) {
-	s.proc = exec.Command(s.bin, s.Config.Command.Args...) // #nosec G204
-	s.proc.Env = s.Env
-	s.proc.Dir = s.wd
-	s.proc.SysProcAttr = &syscall.SysProcAttr{}
-	s.proc.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(s.uid), Gid: uint32(s.gid)}
+	s.passListeners(proc)
 
-	if !s.Config.Command.IgnoreOutput {
+	if s.Config.Type == ServiceType_Getty {
+		var tty *os.File
+
+		tty, err = s.attachTTY(proc)
+		if err != nil {
+			return
+		}
+
+		defer tty.Close() // #nosec: G307
+	} else if !s.Config.Command.IgnoreOutput {
 		for _, f := range []func() error{
 			s.mkLogdir,
-			s.streamStdout,
-			s.streamStderr,
+			func() error { return s.streamStdout(proc) },
+			func() error { return s.streamStderr(proc) },
 		} {
 			err = f()
 			if err != nil {
@@ -170,89 +324,373 @@ func (s *Service) start() (err error) {
 		}
 	}
 
-	err = s.proc.Start()
+	sugar.Debugw("starting process",
+		"service", s.Name,
+		"bin", s.bin,
+		"args", s.Config.Command.Args,
+		"uid", s.uid,
+		"gid", s.gid,
+	)
+
+	err = proc.Start()
 	if err != nil {
+		s.state.Lock()
+		s.status.Pid = 0
+		s.state.Unlock()
+
 		return
 	}
 
-	s.status.Pid = s.proc.Process.Pid
+	s.state.Lock()
+	s.proc = proc
+	s.done = done
+	s.status.Pid = proc.Process.Pid
+	s.state.Unlock()
 
-	err = s.proc.Wait()
+	signalStarted()
 
-	s.status.Running = false
+	stopTimeout := func() bool { return false }
+	if s.Config.Timeout > 0 {
+		stopTimeout = s.watchTimeout(proc.Process.Pid, done)
+	}
+
+	err = proc.Wait()
+	timedOut := stopTimeout()
+
+	s.state.Lock()
 	s.status.EndTime = time.Now()
-	s.status.ExitStatus = s.proc.ProcessState.ExitCode()
+	s.status.recordExit(proc.ProcessState)
+
+	if timedOut {
+		s.status.TimedOut = true
+		err = s.timeoutError()
+	}
+	s.state.Unlock()
+
+	return
+}
 
+// finish records the end of the current run of s, which completed
+// with err.
+//
+// Callers must hold s.state
+func (s *Service) finish(err error) {
 	s.proc = nil
+	s.status.Running = false
+	s.status.Error = err
+
+	switch s.Config.Type {
+	case ServiceType_Service:
+		// if we get here, the service has failed
+		s.status.Success = false
+
+	case ServiceType_Cron:
+		// crons are errors unless they exit 0
+		s.status.Success = s.status.ExitStatus == 0
+
+	case ServiceType_Oneoff:
+		// oneoffs have a list of valid exits
+		s.status.Success = s.Config.Oneoff.Success(s.status.ExitStatus)
+	}
+
+	// a timed out run has failed, whatever it exited with
+	if s.status.TimedOut {
+		s.status.Success = false
+	}
+
+	s.addRun(err)
+
+	// gettys are respawned, rather than failing
+	if s.status.Success || s.Config.Type == ServiceType_Getty {
+		return
+	}
+
+	sugar.Errorw("service finishes unexpectedly",
+		"status", s.status.ExitStatus,
+		"signal", unix.SignalName(s.status.Signal),
+		"service", s.Name,
+		"error", fmt.Sprint(s.status.Error),
+	)
+}
+
+// onDemand returns true for services which are started by events,
+// such as connections, triggers, or schedules, rather than on boot
+func (s *Service) onDemand() bool {
+	return (s.Config.Socket != nil && s.Config.Socket.Lazy) || s.Config.Trigger != nil || s.Config.Type == ServiceType_Cron
+}
+
+// arm starts watching for the events which start an on demand service;
+// connections to its sockets, for lazy services, its triggers, and
+// its schedule, for crons
+func (s *Service) arm() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = s.listen()
+	if err != nil {
+		return
+	}
+
+	s.state.Lock()
+	if s.disarmed != nil {
+		s.state.Unlock()
+
+		return
+	}
+
+	disarmed := make(chan struct{})
+	s.disarmed = disarmed
+	s.state.Unlock()
+
+	if s.Config.Socket != nil && s.Config.Socket.Lazy {
+		go s.watchSockets(s.listeners, disarmed)
+	}
+
+	if s.Config.Trigger != nil {
+		go s.watchTriggers(disarmed, triggerPollInterval)
+	}
+
+	if s.Config.Type == ServiceType_Cron {
+		go s.watchSchedule(disarmed, cronPollInterval)
+	}
 
 	return
 }
 
+// disarm stops an on demand service from being started by events,
+// returning whether it was armed. It doesn't stop the service, should
+// it be running
+func (s *Service) disarm() (wasArmed bool) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	if s.disarmed == nil {
+		return false
+	}
+
+	close(s.disarmed)
+	s.disarmed = nil
+
+	return true
+}
+
+// isClosed returns true once ch, such as the channel a watcher is
+// disarmed by, has been closed
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// rearm takes over from old, the previously loaded config of s, as
+// far as on demand starts go
+func (s *Service) rearm(old *Service) {
+	s.adoptListeners(old)
+
+	if !old.disarm() {
+		return
+	}
+
+	if !s.onDemand() {
+		return
+	}
+
+	err := s.arm()
+	if err != nil {
+		sugar.Errorw("could not watch for events",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+}
+
 func (s *Service) Stop() (err error) {
-	if !s.isRunning() {
+	return s.StopContext(context.Background())
+}
+
+// StopContext stops s, as Stop does, except that s is killed straight
+// away, rather than after its stop timeout, once ctx is cancelled
+func (s *Service) StopContext(ctx context.Context) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop(ctx)
+}
+
+// stop stops s, returning once it has exited.
+//
+// Callers must hold s.mu
+func (s *Service) stop(ctx context.Context) (err error) {
+	// take a copy of s.proc; the goroutine which started this
+	// process sets s.proc to nil once it exits, which it may
+	// well do as soon as we signal it
+	s.state.Lock()
+	proc := s.proc
+	done := s.done
+
+	// a getty between respawns has no process to stop, but is
+	// stopped all the same by its respawn loop being stopped
+	respawning := s.respawning != nil
+	s.stopRespawning()
+
+	if proc == nil && !respawning {
+		s.state.Unlock()
+
 		return fmt.Errorf("service is not running")
 	}
 
+	s.stopping = true
+
+	if proc == nil {
+		s.state.Unlock()
+
+		return
+	}
+
 	s.status.EndTime = time.Now()
+	s.state.Unlock()
+
+	err = proc.Process.Signal(s.Config.StopSignal.s)
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return
+	}
+
+	err = nil
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		sugar.Warnw("out of time to stop service, killing",
+			"service", s.Name,
+		)
+
+		err = kill(proc, done)
+	case <-time.After(s.Config.StopTimeout):
+		sugar.Warnw("service did not stop in time, killing",
+			"service", s.Name,
+			"timeout", s.Config.StopTimeout.String(),
+		)
+
+		err = kill(proc, done)
+	}
 
-	err = s.proc.Process.Kill()
 	if err != nil {
 		return
 	}
 
+	s.state.Lock()
 	s.status.Running = false
-	s.status.ExitStatus = s.proc.ProcessState.ExitCode()
+	s.status.recordExit(proc.ProcessState)
+	s.state.Unlock()
 
 	return
 }
 
+// kill sends SIGKILL to proc, returning once done is closed
+func kill(proc *exec.Cmd, done chan struct{}) (err error) {
+	err = proc.Process.Kill()
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return
+	}
+
+	<-done
+
+	return nil
+}
+
 func (s *Service) Status() (status ServiceStatus, err error) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
 	return s.status, nil
 }
 
 func (s *Service) Reload() (err error) {
-	if !s.isRunning() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reload()
+}
+
+// reload signals s to reload.
+//
+// Callers must hold s.mu
+func (s *Service) reload() (err error) {
+	proc := s.process()
+	if proc == nil {
 		return fmt.Errorf("service is not running")
 	}
 
-	return s.proc.Process.Signal(s.Config.ReloadSignal.s)
+	return proc.Process.Signal(s.Config.ReloadSignal.s)
+}
+
+func (s *Service) isRunning() bool {
+	return s.process() != nil
+}
+
+// process returns the running process of s, or nil when s isn't
+// running
+func (s *Service) process() *exec.Cmd {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	return s.proc
+}
+
+// startCount returns how often s has been started since vinit started
+func (s *Service) startCount() int {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	return s.starts
 }
 
-func (s Service) isRunning() bool {
-	return s.proc != nil && s.proc.Process != nil
+// stopRespawning stops the respawn loop of a getty, should it have one.
+//
+// Callers must hold s.state
+func (s *Service) stopRespawning() {
+	if s.respawning == nil {
+		return
+	}
+
+	close(s.respawning)
+	s.respawning = nil
 }
 
 func (s *Service) mkLogdir() error {
 	return os.MkdirAll(s.logdir, 0700)
 }
 
-func (s *Service) streamStdout() (err error) {
+func (s *Service) streamStdout(proc *exec.Cmd) (err error) {
 	stdout, err := os.OpenFile(filepath.Join(s.logdir, "stdout"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil || s.proc == nil {
-		s.proc.Stdout = io.Discard
+	if err != nil {
+		proc.Stdout = io.Discard
 
 		return
 	}
 
-	s.proc.Stdout = stdout
+	proc.Stdout = stdout
 
 	return
 }
 
-func (s *Service) streamStderr() (err error) {
+func (s *Service) streamStderr(proc *exec.Cmd) (err error) {
 	stderr, err := os.OpenFile(filepath.Join(s.logdir, "stderr"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil || s.proc == nil {
-		s.proc.Stderr = io.Discard
+	if err != nil {
+		proc.Stderr = io.Discard
 
 		return
 	}
 
-	s.proc.Stderr = stderr
+	proc.Stderr = stderr
 
 	return
 }
 
-func (s Service) validateBin() (err error) {
+func (s *Service) validateBin() (err error) {
 	f, err := os.Stat(s.bin)
 	if err != nil {
 		return fmt.Errorf("could not open file %s", s.bin)
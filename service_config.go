@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"math/rand"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,10 +17,15 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// defaultStopTimeout is how long a service is given to exit after
+// being sent its stop signal, before being killed
+const defaultStopTimeout = 10 * time.Second
+
 const (
 	ServiceType_Service ServiceType = iota
 	ServiceType_Cron
 	ServiceType_Oneoff
+	ServiceType_Getty
 )
 
 // ServiceType provides an enum type to track the type of service
@@ -26,6 +34,7 @@ const (
 //  1. ServiceType_Service, represented by "service" in config. Long running, to be restarted
 //  2. ServiceType_Cron, represented by "cron" in config. Runs on schedule, expects to finish
 //  3. ServiceType_Oneoff, represented by "oneoff" in config. Runs once on boot.
+//  4. ServiceType_Getty, represented by "getty" in config. Owns a terminal, respawning on logout
 type ServiceType int8
 
 // UnmarshalText provides the Unmarshal interface for ServiceType
@@ -39,14 +48,30 @@ func (s *ServiceType) UnmarshalText(text []byte) (err error) {
 		*s = ServiceType_Cron
 	case "oneoff":
 		*s = ServiceType_Oneoff
+	case "getty":
+		*s = ServiceType_Getty
 	default:
-		err = fmt.Errorf("invalid type %q; must be in set (%q,%q,%q)",
-			t, "service", "cron", "oneoff")
+		err = fmt.Errorf("invalid type %q; must be in set (%q,%q,%q,%q)",
+			t, "service", "cron", "oneoff", "getty")
 	}
 
 	return
 }
 
+// String returns the config representation of s
+func (s ServiceType) String() string {
+	switch s {
+	case ServiceType_Cron:
+		return "cron"
+	case ServiceType_Oneoff:
+		return "oneoff"
+	case ServiceType_Getty:
+		return "getty"
+	}
+
+	return "service"
+}
+
 // ReloadSignal holds an os.Signal which is sent to a process on `vinitctl reload process`
 type ReloadSignal struct {
 	s os.Signal
@@ -72,6 +97,31 @@ func (r *ReloadSignal) UnmarshalText(text []byte) (err error) {
 	return nil
 }
 
+// StopSignal holds an os.Signal which is sent to a process to ask it to stop
+type StopSignal struct {
+	s os.Signal
+}
+
+// UnmarshalText provides the Unmarshal interface for StopSignal.
+//
+// The default signal is SIGTERM.
+func (st *StopSignal) UnmarshalText(text []byte) (err error) {
+	if len(text) == 0 {
+		st.s = syscall.SIGTERM
+
+		return nil
+	}
+
+	s := unix.SignalNum(string(text))
+	if s == 0 {
+		return fmt.Errorf("invalid signal %q", string(text))
+	}
+
+	st.s = s
+
+	return nil
+}
+
 // Args are the arguments set for a service.
 type Args []string
 
@@ -150,14 +200,37 @@ type Grouping struct {
 	GroupName string `toml:"name"`
 }
 
+// scheduleParser parses standard crontab schedules, with an optional
+// leading seconds field, and descriptors such as @daily and @every 5m
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// rebootDescriptor schedules a cron to run once, on boot
+const rebootDescriptor = "@reboot"
+
 // Schedule wraps a cron schedule so we can write an unmarshaller
 type Schedule struct {
 	cron.Schedule
+
+	// Reboot is set for @reboot schedules, which have no
+	// cron.Schedule
+	Reboot bool
+
+	text string
 }
 
 // UnmarshalText implements the Unmarshal interface
 func (s *Schedule) UnmarshalText(text []byte) (err error) {
-	sched, err := cron.ParseStandard(string(text))
+	s.text = string(text)
+
+	if s.text == rebootDescriptor {
+		s.Reboot = true
+
+		return
+	}
+
+	sched, err := scheduleParser.Parse(s.text)
 	if err != nil {
 		return
 	}
@@ -167,10 +240,105 @@ func (s *Schedule) UnmarshalText(text []byte) (err error) {
 	return
 }
 
+// String returns the schedule as configured
+func (s Schedule) String() string {
+	return s.text
+}
+
+const (
+	OverlapPolicy_Skip OverlapPolicy = iota
+	OverlapPolicy_Queue
+	OverlapPolicy_Replace
+)
+
+// OverlapPolicy governs what happens when a cron is due to run while
+// its previous run is still going; namely:
+//
+//  1. OverlapPolicy_Skip, represented by "skip" in config. The new run doesn't happen (the default)
+//  2. OverlapPolicy_Queue, represented by "queue" in config. The new run happens once the previous run finishes
+//  3. OverlapPolicy_Replace, represented by "replace" in config. The previous run is stopped, and the new run started
+type OverlapPolicy int8
+
+// UnmarshalText provides the Unmarshal interface for OverlapPolicy
+func (o *OverlapPolicy) UnmarshalText(text []byte) (err error) {
+	t := string(text)
+
+	switch t {
+	case "skip":
+		*o = OverlapPolicy_Skip
+	case "queue":
+		*o = OverlapPolicy_Queue
+	case "replace":
+		*o = OverlapPolicy_Replace
+	default:
+		err = fmt.Errorf("invalid overlap policy %q; must be in set (%q,%q,%q)",
+			t, "skip", "queue", "replace")
+	}
+
+	return
+}
+
+// String returns the config representation of o
+func (o OverlapPolicy) String() string {
+	switch o {
+	case OverlapPolicy_Queue:
+		return "queue"
+	case OverlapPolicy_Replace:
+		return "replace"
+	}
+
+	return "skip"
+}
+
 // Cron holds specific configs used just by services of type
 // ServiceType_Cron
 type Cron struct {
-	Schedule Schedule `toml:"schedule"`
+	Schedule Schedule      `toml:"schedule"`
+	Overlap  OverlapPolicy `toml:"overlap"`
+
+	// CatchUp runs the cron once on boot, should a scheduled run
+	// have been missed while the machine was down
+	CatchUp bool `toml:"catch_up"`
+
+	// TimeZone is the IANA time zone Schedule is in, overriding
+	// any CRON_TZ in the schedule itself. Defaults to local time
+	TimeZone string `toml:"time_zone"`
+
+	// RandomDelay delays each run by a random duration of up to
+	// this long, so that many machines sharing a schedule don't
+	// all run at once
+	RandomDelay time.Duration `toml:"random_delay"`
+}
+
+// Next returns the next time a cron should run after t, including any
+// random delay. @reboot crons run as soon as they're able to
+func (c Cron) Next(t time.Time) time.Time {
+	next := t
+	if !c.Schedule.Reboot {
+		next = c.Schedule.Next(t)
+	}
+
+	if c.RandomDelay > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(c.RandomDelay)))) // #nosec: G404
+	}
+
+	return next
+}
+
+// String describes when a cron runs
+func (c Cron) String() string {
+	sb := new(strings.Builder)
+	sb.WriteString(c.Schedule.String())
+
+	if c.TimeZone != "" {
+		sb.WriteString(" (" + c.TimeZone + ")")
+	}
+
+	if c.RandomDelay > 0 {
+		sb.WriteString(", delayed by up to " + c.RandomDelay.String())
+	}
+
+	return sb.String()
 }
 
 // Oneoff holds specific configs used just by services of type
@@ -191,6 +359,122 @@ func (o Oneoff) Success(exitCode int) bool {
 	return false
 }
 
+// TTYs holds the terminals a getty runs on, and may be configured
+// as either a single tty, or a list of ttys
+type TTYs []string
+
+// UnmarshalTOML implements the toml.Unmarshaler interface
+func (t *TTYs) UnmarshalTOML(v interface{}) (err error) {
+	switch tv := v.(type) {
+	case string:
+		*t = TTYs{tv}
+
+	case []interface{}:
+		ttys := make(TTYs, len(tv))
+		for i, elem := range tv {
+			tty, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("invalid tty %v", elem)
+			}
+
+			ttys[i] = tty
+		}
+
+		*t = ttys
+
+	default:
+		err = fmt.Errorf("invalid tty %v; must be a string or a list of strings", v)
+	}
+
+	return
+}
+
+// Getty holds specific configs used just by services of type
+// ServiceType_Getty
+type Getty struct {
+	TTYs TTYs   `toml:"tty"`
+	Baud int    `toml:"baud"`
+	Term string `toml:"term"`
+}
+
+// ListenAddress is an address vinit listens on for a service, in the
+// form "tcp://127.0.0.1:8080", "udp://:53", or "unix:///run/app.sock"
+type ListenAddress struct {
+	Network string
+	Address string
+}
+
+// UnmarshalText provides the Unmarshal interface for ListenAddress
+func (l *ListenAddress) UnmarshalText(text []byte) (err error) {
+	t := string(text)
+
+	network, address, ok := strings.Cut(t, "://")
+	if !ok || address == "" {
+		return fmt.Errorf("invalid listen address %q; must be in the form network://address", t)
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix":
+	default:
+		return fmt.Errorf("invalid listen network %q; must be in set (%q,%q,%q,%q,%q,%q,%q)",
+			network, "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix")
+	}
+
+	l.Network = network
+	l.Address = address
+
+	return
+}
+
+// String returns the config representation of a ListenAddress
+func (l ListenAddress) String() string {
+	return l.Network + "://" + l.Address
+}
+
+// Socket holds the listeners vinit binds for a service, and passes
+// to it on start, in the style of systemd socket activation
+type Socket struct {
+	Listen []ListenAddress `toml:"listen"`
+
+	// Lazy delays starting the service until a connection
+	// arrives on one of Listen, rather than on boot
+	Lazy bool `toml:"lazy"`
+}
+
+// Trigger holds the events which start a service on demand, rather
+// than on boot
+type Trigger struct {
+	// Paths start the service whenever one of them is created,
+	// changed, or removed (or, for directories, whenever their
+	// contents are)
+	Paths []string `toml:"path"`
+
+	// OnBoot starts the service this long after the machine
+	// booted
+	OnBoot BootDelay `toml:"on_boot"`
+
+	// Interval starts the service this long after it last
+	// finished running
+	Interval time.Duration `toml:"interval"`
+}
+
+// BootDelay is how long after the machine booted a trigger fires. It
+// must be given as a duration string, such as "30s", rather than as a
+// number, which would otherwise be read as nanoseconds
+type BootDelay time.Duration
+
+// UnmarshalText provides the Unmarshal interface for BootDelay
+func (b *BootDelay) UnmarshalText(text []byte) (err error) {
+	d, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid on_boot %q: must be a duration, such as \"30s\"", string(text))
+	}
+
+	*b = BootDelay(d)
+
+	return
+}
+
 // Command holds extra arguments and config for the process
 // started for the service
 type Command struct {
@@ -203,11 +487,23 @@ type Command struct {
 type ServiceConfig struct {
 	Type         ServiceType   `toml:"type"`
 	ReloadSignal *ReloadSignal `toml:"reload_signal"`
+	StopSignal   *StopSignal   `toml:"stop_signal"`
+	StopTimeout  time.Duration `toml:"stop_timeout"`
+	Critical     bool          `toml:"critical"`
 	User         User          `toml:"user"`
 	Grouping     Grouping      `toml:"grouping"`
 	Cron         *Cron         `toml:"cron,omitempty"`
 	Oneoff       *Oneoff       `toml:"oneoff,omitemoty"`
+	Getty        *Getty        `toml:"getty,omitempty"`
+	Socket       *Socket       `toml:"socket,omitempty"`
+	Trigger      *Trigger      `toml:"trigger,omitempty"`
 	Command      Command       `toml:"command"`
+
+	// Timeout, for oneoffs and crons, is how long a run may take
+	// before its process group is sent TimeoutSignal (and, should
+	// it still be running StopTimeout later, SIGKILL)
+	Timeout       time.Duration `toml:"timeout"`
+	TimeoutSignal *StopSignal   `toml:"timeout_signal"`
 }
 
 // LoadServiceConfig decodes a toml file
@@ -220,8 +516,9 @@ func LoadServiceConfig(fn string) (s ServiceConfig, err error) {
 	switch s.Type {
 	case ServiceType_Service:
 	case ServiceType_Cron:
-		if s.Cron == nil || s.Cron.Schedule.Schedule == nil || (s.Cron.Schedule.Next(time.Now()) == time.Time{}) {
-			err = fmt.Errorf("invalid cron schedule")
+		err = s.validateCron()
+		if err != nil {
+			return
 		}
 	case ServiceType_Oneoff:
 		if s.Oneoff == nil {
@@ -233,6 +530,25 @@ func LoadServiceConfig(fn string) (s ServiceConfig, err error) {
 		if len(s.Oneoff.ValidCodes) == 0 {
 			s.Oneoff.ValidCodes = []int{0}
 		}
+	case ServiceType_Getty:
+		err = s.validateGetty()
+		if err != nil {
+			return
+		}
+	}
+
+	if s.Socket != nil {
+		err = s.validateSocket()
+		if err != nil {
+			return
+		}
+	}
+
+	if s.Trigger != nil {
+		err = s.validateTrigger()
+		if err != nil {
+			return
+		}
 	}
 
 	if s.Grouping.GroupName == "" {
@@ -255,5 +571,115 @@ func LoadServiceConfig(fn string) (s ServiceConfig, err error) {
 		}
 	}
 
+	if s.StopSignal == nil || s.StopSignal.s == nil {
+		s.StopSignal = &StopSignal{
+			s: syscall.SIGTERM,
+		}
+	}
+
+	if s.StopTimeout <= 0 {
+		s.StopTimeout = defaultStopTimeout
+	}
+
+	err = s.validateTimeout()
+	if err != nil {
+		return
+	}
+
+	if s.TimeoutSignal == nil || s.TimeoutSignal.s == nil {
+		s.TimeoutSignal = &StopSignal{
+			s: syscall.SIGTERM,
+		}
+	}
+
 	return
 }
+
+func (s *ServiceConfig) validateTimeout() error {
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	if (s.Timeout > 0 || s.TimeoutSignal != nil) && s.Type != ServiceType_Oneoff && s.Type != ServiceType_Cron {
+		return fmt.Errorf("timeouts may only be used by services of type %q or %q", "oneoff", "cron")
+	}
+
+	return nil
+}
+
+func (s *ServiceConfig) validateCron() error {
+	if s.Cron == nil || (!s.Cron.Schedule.Reboot && s.Cron.Schedule.Schedule == nil) {
+		return fmt.Errorf("invalid cron schedule")
+	}
+
+	if s.Cron.RandomDelay < 0 {
+		return fmt.Errorf("random_delay must not be negative")
+	}
+
+	if s.Cron.TimeZone != "" {
+		loc, err := time.LoadLocation(s.Cron.TimeZone)
+		if err != nil {
+			return fmt.Errorf("invalid time zone %q: %w", s.Cron.TimeZone, err)
+		}
+
+		if spec, ok := s.Cron.Schedule.Schedule.(*cron.SpecSchedule); ok {
+			spec.Location = loc
+		}
+	}
+
+	if !s.Cron.Schedule.Reboot && (s.Cron.Schedule.Next(time.Now()) == time.Time{}) {
+		return fmt.Errorf("invalid cron schedule")
+	}
+
+	return nil
+}
+
+func (s *ServiceConfig) validateGetty() error {
+	if s.Getty == nil || len(s.Getty.TTYs) == 0 {
+		return fmt.Errorf("missing getty tty")
+	}
+
+	if s.Getty.Baud == 0 {
+		s.Getty.Baud = defaultBaud
+	}
+
+	if _, ok := baudRates[s.Getty.Baud]; !ok {
+		return fmt.Errorf("invalid baud rate %d", s.Getty.Baud)
+	}
+
+	if s.Getty.Term == "" {
+		s.Getty.Term = defaultTerm
+	}
+
+	return nil
+}
+
+func (s *ServiceConfig) validateSocket() error {
+	if s.Type != ServiceType_Service {
+		return fmt.Errorf("sockets may only be used by services of type %q", "service")
+	}
+
+	if len(s.Socket.Listen) == 0 {
+		return fmt.Errorf("missing socket listen address")
+	}
+
+	return nil
+}
+
+func (s *ServiceConfig) validateTrigger() error {
+	if s.Type != ServiceType_Service && s.Type != ServiceType_Oneoff {
+		return fmt.Errorf("triggers may only be used by services of type %q or %q", "service", "oneoff")
+	}
+
+	if len(s.Trigger.Paths) == 0 && s.Trigger.OnBoot <= 0 && s.Trigger.Interval <= 0 {
+		return fmt.Errorf("trigger must contain at least one of path, on_boot, or interval")
+	}
+
+	for _, p := range s.Trigger.Paths {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("trigger path %q must be absolute", p)
+		}
+	}
+
+	return nil
+}
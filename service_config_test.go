@@ -13,23 +13,50 @@ func TestServiceConfig(t *testing.T) {
 		{"service happy path", "testdata/services/00-app/.config.toml", false},
 		{"cronjob happy path", "testdata/services/00-app-cronjob/.config.toml", false},
 		{"oneoff happy path", "testdata/services/00-app-oneoff/.config.toml", false},
+		{"getty happy path", "testdata/successing/getty.toml", false},
 
 		// edge cases and errors
 		{"invalid type", "testdata/erroring/wrong-type.toml", true},
 		{"missing cron schedule", "testdata/erroring/missing-cron.toml", true},
 		{"invalid cron schedule", "testdata/erroring/invalid-cron.toml", true},
 		{"missing oneoff", "testdata/erroring/missing-oneoff.toml", true},
+		{"missing getty", "testdata/erroring/missing-getty.toml", true},
+		{"invalid baud rate", "testdata/erroring/invalid-baud.toml", true},
+		{"invalid tty", "testdata/erroring/invalid-tty.toml", true},
 		{"missing group name", "testdata/erroring/missing-groupname.toml", true},
 		{"invalid signal errors out", "testdata/erroring/invalid-signal.toml", true},
+		{"invalid stop signal errors out", "testdata/erroring/invalid-stop-signal.toml", true},
 		{"missing args is fine", "testdata/successing/missing-args.toml", false},
 		{"missing user sets user to root", "testdata/successing/missing-user.toml", false},
 		{"empty validcodes gets a default", "testdata/successing/empty-validcodes.toml", false},
 		{"empty reload signal gets a default", "testdata/successing/empty-reloadsignal.toml", false},
+		{"stop signal and timeout", "testdata/successing/stop.toml", false},
+		{"sockets", "testdata/successing/socket.toml", false},
+		{"invalid listen address errors out", "testdata/erroring/invalid-listen.toml", true},
+		{"missing listen address errors out", "testdata/erroring/missing-listen.toml", true},
+		{"sockets on oneoffs error out", "testdata/erroring/socket-oneoff.toml", true},
+		{"triggers", "testdata/successing/trigger.toml", false},
+		{"triggers on crons error out", "testdata/erroring/trigger-cron.toml", true},
+		{"empty trigger errors out", "testdata/erroring/empty-trigger.toml", true},
+		{"relative trigger path errors out", "testdata/erroring/relative-trigger-path.toml", true},
+		{"integer on_boot errors out", "testdata/erroring/trigger-integer-on-boot.toml", true},
+		{"cron overlap and catch up", "testdata/successing/cron-overlap.toml", false},
+		{"invalid overlap policy errors out", "testdata/erroring/invalid-overlap.toml", true},
+		{"cron with seconds, time zone, and random delay", "testdata/successing/cron-seconds.toml", false},
+		{"cron using @every", "testdata/successing/cron-every.toml", false},
+		{"cron using @reboot", "testdata/successing/cron-reboot.toml", false},
+		{"invalid time zone errors out", "testdata/erroring/invalid-time-zone.toml", true},
+		{"negative random delay errors out", "testdata/erroring/negative-random-delay.toml", true},
+		{"timeouts", "testdata/successing/timeout.toml", false},
+		{"timeouts on services error out", "testdata/erroring/timeout-service.toml", true},
+		{"negative timeout errors out", "testdata/erroring/negative-timeout.toml", true},
+		{"invalid timeout signal errors out", "testdata/erroring/invalid-timeout-signal.toml", true},
 
 		// minimal viable configs
 		{"minimal viable service", "testdata/mvs/service.toml", false},
 		{"minimal viable cronjob", "testdata/mvs/cron.toml", false},
 		{"minimal viable oneoff", "testdata/mvs/oneoff.toml", false},
+		{"minimal viable getty", "testdata/mvs/getty.toml", false},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			_, err := LoadServiceConfig(test.fn)
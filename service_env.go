@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"errors"
 	"os"
+	"regexp"
+	"strings"
 )
 
 type EnvVars []string
@@ -33,3 +35,51 @@ func LoadEnvVars(fn string) (ev EnvVars, err error) {
 
 	return
 }
+
+// redacted replaces the values of environment variables whose names
+// look like secrets
+const redacted = "<redacted>"
+
+// secretEnvVar matches the names of environment variables which
+// probably hold secrets
+var secretEnvVar = regexp.MustCompile(`(?i)(secret|passw(or)?d|token|credential|private|api_?key|auth)`)
+
+// Merged returns ev with later definitions of a variable replacing
+// earlier ones, as environment_overrides does environment, keeping the
+// order in which each variable first appears
+func (ev EnvVars) Merged() (out EnvVars) {
+	out = make(EnvVars, 0, len(ev))
+	index := make(map[string]int)
+
+	for _, kv := range ev {
+		k, _, _ := strings.Cut(kv, "=")
+
+		if i, ok := index[k]; ok {
+			out[i] = kv
+
+			continue
+		}
+
+		index[k] = len(out)
+		out = append(out, kv)
+	}
+
+	return
+}
+
+// Redacted returns ev with the values of any variables which look like
+// they hold secrets, such as API_TOKEN, replaced
+func (ev EnvVars) Redacted() (out EnvVars) {
+	out = make(EnvVars, len(ev))
+
+	for i, kv := range ev {
+		k, _, _ := strings.Cut(kv, "=")
+		if secretEnvVar.MatchString(k) {
+			kv = k + "=" + redacted
+		}
+
+		out[i] = kv
+	}
+
+	return
+}
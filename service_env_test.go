@@ -31,3 +31,45 @@ func TestLoadEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvVars_Merged(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		ev     EnvVars
+		expect EnvVars
+	}{
+		{"empty", EnvVars{}, EnvVars{}},
+		{"no duplicates", EnvVars{"A=1", "B=2"}, EnvVars{"A=1", "B=2"}},
+		{"overridden", EnvVars{"A=1", "B=2", "A=3"}, EnvVars{"A=3", "B=2"}},
+		{"values containing =", EnvVars{"A=b=c", "A=d=e"}, EnvVars{"A=d=e"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.ev.Merged()
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestEnvVars_Redacted(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		ev     EnvVars
+		expect EnvVars
+	}{
+		{"nothing secret", EnvVars{"PATH=/bin:/sbin"}, EnvVars{"PATH=/bin:/sbin"}},
+		{"token", EnvVars{"API_TOKEN=abc"}, EnvVars{"API_TOKEN=<redacted>"}},
+		{"password", EnvVars{"db_password=abc"}, EnvVars{"db_password=<redacted>"}},
+		{"mixed", EnvVars{"HELLO=world", "AWS_SECRET_ACCESS_KEY=abc"}, EnvVars{"HELLO=world", "AWS_SECRET_ACCESS_KEY=<redacted>"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.ev.Redacted()
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+const (
+	// defaultServiceLogLines is how many lines of each log
+	// ServiceLogs returns, where the caller doesn't say, and
+	// maxServiceLogLines is the most it'll return
+	defaultServiceLogLines = 50
+	maxServiceLogLines     = 1000
+
+	// serviceLogTailBytes is how far back from the end of a log
+	// file ServiceLogs looks for lines, so that huge logs (which
+	// vinit doesn't rotate) don't need reading in full
+	serviceLogTailBytes = 1 << 20
+)
+
+// ServiceLogs returns the last lines a service wrote to stdout and
+// stderr
+func (d Dispatcher) ServiceLogs(_ context.Context, r *dispatcher.ServiceLogsRequest) (out *dispatcher.ServiceLogsMessage, err error) {
+	out = new(dispatcher.ServiceLogsMessage)
+
+	name := r.GetService().GetName()
+	if name == "" {
+		return out, errNoService
+	}
+
+	svc, ok := d.s.service(name)
+	if !ok {
+		return out, errServiceNotExist
+	}
+
+	lines := int(r.GetLines())
+
+	switch {
+	case lines == 0:
+		lines = defaultServiceLogLines
+
+	case lines > maxServiceLogLines:
+		lines = maxServiceLogLines
+	}
+
+	logdir := svc.logdir
+	if logdir == "" {
+		// services which failed to load may still have logs
+		// from before they broke
+		logdir = filepath.Join(svc.dir, "logs")
+	}
+
+	out.Stdout, err = tailLines(filepath.Join(logdir, "stdout"), lines)
+	if err != nil {
+		return
+	}
+
+	out.Stderr, err = tailLines(filepath.Join(logdir, "stderr"), lines)
+
+	return
+}
+
+// tailLines returns up to the last n lines of the file fn. A file which
+// doesn't exist has no lines
+func tailLines(fn string, n int) (lines []string, err error) {
+	lines = make([]string, 0)
+
+	f, err := os.Open(fn) // #nosec: G304
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	var offset int64
+	if fi.Size() > serviceLogTailBytes {
+		offset = fi.Size() - serviceLogTailBytes
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return
+	}
+
+	all := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	// having started part way through the file, the first line is
+	// very likely to be incomplete
+	if offset > 0 && len(all) > 0 {
+		all = all[1:]
+	}
+
+	if len(all) == 1 && all[0] == "" {
+		return
+	}
+
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+
+	return append(lines, all...), nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestDispatcher_ServiceLogs(t *testing.T) {
+	d := newDispatcher()
+
+	logdir := t.TempDir()
+	d.s.services["app"].logdir = logdir
+
+	err := os.WriteFile(filepath.Join(logdir, "stdout"), []byte("one\ntwo\nthree\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name         string
+		r            *dispatcher.ServiceLogsRequest
+		expectStdout []string
+		expectStderr []string
+		expectError  error
+	}{
+		{"nil request", nil, []string(nil), []string(nil), errNoService},
+		{"unknown service", &dispatcher.ServiceLogsRequest{Service: &dispatcher.Service{Name: "nonesuch"}}, []string(nil), []string(nil), errServiceNotExist},
+		{"default lines", &dispatcher.ServiceLogsRequest{Service: &dispatcher.Service{Name: "app"}}, []string{"one", "two", "three"}, []string{}, nil},
+		{"last line only", &dispatcher.ServiceLogsRequest{Service: &dispatcher.Service{Name: "app"}, Lines: 1}, []string{"three"}, []string{}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := d.ServiceLogs(context.Background(), test.r)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+
+			if !reflect.DeepEqual(test.expectStdout, out.Stdout) {
+				t.Errorf("expected %#v, received %#v", test.expectStdout, out.Stdout)
+			}
+
+			if !reflect.DeepEqual(test.expectStderr, out.Stderr) {
+				t.Errorf("expected %#v, received %#v", test.expectStderr, out.Stderr)
+			}
+		})
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "stdout")
+
+	// a log longer than serviceLogTailBytes, where the last line
+	// is all that fits
+	long := strings.Repeat("a", serviceLogTailBytes) + "\nlast\n"
+
+	err := os.WriteFile(fn, []byte(long), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := tailLines(fn, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	expect := []string{"last"}
+	if !reflect.DeepEqual(expect, lines) {
+		t.Errorf("expected %#v, received %d lines", expect, len(lines))
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	// disabledMarker, when present in a service directory, stops
+	// that service from being started on boot
+	disabledMarker = ".disabled"
+
+	// maskedMarker, when present in a service directory, stops
+	// that service from being started at all
+	maskedMarker = ".masked"
+)
+
+// Enable allows a service to be started on boot
+func (d Dispatcher) Enable(_ context.Context, s *dispatcher.Service) (out *emptypb.Empty, err error) {
+	return d.setMarker(s, disabledMarker, false)
+}
+
+// Disable stops a service from being started on boot, while still
+// allowing it to be started by hand
+func (d Dispatcher) Disable(_ context.Context, s *dispatcher.Service) (out *emptypb.Empty, err error) {
+	return d.setMarker(s, disabledMarker, true)
+}
+
+// Mask stops a service from being started at all
+func (d Dispatcher) Mask(_ context.Context, s *dispatcher.Service) (out *emptypb.Empty, err error) {
+	return d.setMarker(s, maskedMarker, true)
+}
+
+// Unmask allows a masked service to be started again
+func (d Dispatcher) Unmask(_ context.Context, s *dispatcher.Service) (out *emptypb.Empty, err error) {
+	return d.setMarker(s, maskedMarker, false)
+}
+
+func (d Dispatcher) setMarker(s *dispatcher.Service, marker string, set bool) (out *emptypb.Empty, err error) {
+	out = new(emptypb.Empty)
+
+	if s == nil || s.Name == "" {
+		return out, errNoService
+	}
+
+	return out, d.s.setMarker(s.Name, marker, set)
+}
+
+// setMarker creates or removes marker in the directory of the service
+// name. Instances of a service share a directory, and so share markers
+func (s *Supervisor) setMarker(name, marker string, set bool) (err error) {
+	svc, ok := s.service(name)
+	if !ok {
+		return errServiceNotExist
+	}
+
+	err = svc.setMarker(marker, set)
+	if err != nil {
+		return
+	}
+
+	// a masked service mustn't be started by connections, triggers,
+	// or schedules either; masking one instance masks them all, and
+	// unmasking it lets them be started by those events again
+	if marker == maskedMarker {
+		_, _, services := s.loaded()
+
+		for _, other := range services {
+			if other.dir != svc.dir {
+				continue
+			}
+
+			if set {
+				other.disarm()
+
+				continue
+			}
+
+			s.rearmUnmasked(other)
+		}
+	}
+
+	sugar.Infow("service state changed",
+		"service", name,
+		"marker", marker,
+		"set", set,
+	)
+
+	return
+}
+
+// rearmUnmasked starts watching for the events which start svc again,
+// once it's unmasked, so long as it would have been armed on boot
+func (s *Supervisor) rearmUnmasked(svc *Service) {
+	if !svc.onDemand() || s.masked(svc.Name) || svc.isDisabled() {
+		return
+	}
+
+	err := svc.arm()
+	if err != nil {
+		sugar.Errorw("could not watch for events",
+			"service", svc.Name,
+			"error", err.Error(),
+		)
+	}
+}
+
+func (s *Service) setMarker(marker string, set bool) (err error) {
+	fn := filepath.Join(s.dir, marker)
+
+	if !set {
+		err = os.Remove(fn)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return
+	}
+
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY, 0600) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	return f.Close()
+}
+
+func (s *Service) hasMarker(marker string) bool {
+	_, err := os.Stat(filepath.Join(s.dir, marker))
+
+	return err == nil
+}
+
+// isDisabled returns true when s should not be started on boot
+func (s *Service) isDisabled() bool {
+	return s.hasMarker(disabledMarker)
+}
+
+// isMasked returns true when s should not be started at all
+func (s *Service) isMasked() bool {
+	return s.hasMarker(maskedMarker)
+}
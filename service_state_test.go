@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestDispatcher_ServiceState(t *testing.T) {
+	d := newDispatcher()
+
+	// keep markers out of testdata
+	d.s.services["app"].dir = t.TempDir()
+
+	app := &dispatcher.Service{Name: "app"}
+
+	for _, test := range []struct {
+		name           string
+		f              func(context.Context, *dispatcher.Service) (*emptypb.Empty, error)
+		expectDisabled bool
+		expectMasked   bool
+		expectBootable []string
+	}{
+		{"disable", d.Disable, true, false, []string{"app-cronjob", "app-oneoff"}},
+		{"disabling twice is fine", d.Disable, true, false, []string{"app-cronjob", "app-oneoff"}},
+		{"enable", d.Enable, false, false, []string{"app", "app-cronjob", "app-oneoff"}},
+		{"enabling twice is fine", d.Enable, false, false, []string{"app", "app-cronjob", "app-oneoff"}},
+		{"mask", d.Mask, false, true, []string{"app-cronjob", "app-oneoff"}},
+		{"unmask", d.Unmask, false, false, []string{"app", "app-cronjob", "app-oneoff"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.f(context.Background(), app)
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			status, err := d.Status(context.Background(), app)
+			if err != nil {
+				t.Fatalf("unexpected error %#v", err)
+			}
+
+			if test.expectDisabled != status.Disabled {
+				t.Errorf("expected disabled to be %v", test.expectDisabled)
+			}
+
+			if test.expectMasked != status.Masked {
+				t.Errorf("expected masked to be %v", test.expectMasked)
+			}
+
+			got := d.s.bootable(d.s.groupsServices["system"])
+			if !reflect.DeepEqual(test.expectBootable, got) {
+				t.Errorf("expected %#v, received %#v", test.expectBootable, got)
+			}
+		})
+	}
+
+	t.Run("masked services cannot be started", func(t *testing.T) {
+		_, err := d.Mask(context.Background(), app)
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		_, err = d.Start(context.Background(), app)
+		if err != errServiceMasked {
+			t.Errorf("expected %#v, received %#v", errServiceMasked, err)
+		}
+	})
+}
+
+func TestDispatcher_ServiceState_Errors(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		s           *dispatcher.Service
+		expectError error
+	}{
+		{"nil service", nil, errNoService},
+		{"empty service", &dispatcher.Service{}, errNoService},
+		{"unknown service", &dispatcher.Service{Name: "nonesuch"}, errServiceNotExist},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.Disable(context.Background(), test.s)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+}
@@ -75,3 +75,11 @@ func TestService_Start_UnableToCreateLogs(t *testing.T) {
 	// Give service time to start
 	time.Sleep(time.Millisecond * 100)
 }
+
+// statusOf returns the status of svc, for tests which only care about
+// a field or two of it
+func statusOf(svc *Service) ServiceStatus {
+	status, _ := svc.Status()
+
+	return status
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ShowConfig returns the config of a service as vinit has resolved it;
+// with defaults applied, its group overrides reconciled, and its
+// environment merged (with anything which looks secret redacted)
+func (d Dispatcher) ShowConfig(_ context.Context, s *dispatcher.Service) (out *dispatcher.ServiceConfigMessage, err error) {
+	out = new(dispatcher.ServiceConfigMessage)
+
+	if s == nil || s.Name == "" {
+		return out, errNoService
+	}
+
+	svc, ok := d.s.service(s.Name)
+	if !ok {
+		return out, errServiceNotExist
+	}
+
+	out.Name = svc.Name
+	out.Dir = svc.dir
+	out.Group = d.s.groupOf(svc.Name)
+
+	// a service we couldn't load has no config worth showing
+	if svc.loadError != "" {
+		out.LoadError = svc.loadError
+
+		return
+	}
+
+	c := svc.Config
+
+	out.Type = c.Type.String()
+	out.ConfiguredGroup = c.Grouping.GroupName
+	out.User = c.User.User
+	out.UserGroup = c.User.Group
+	out.Uid = svc.uid
+	out.Gid = svc.gid
+	out.Bin = svc.bin
+	out.Args = c.Command.Args
+	out.Wd = svc.wd
+	out.LogDir = svc.logdir
+	out.IgnoreOutput = c.Command.IgnoreOutput
+	out.Environment = svc.Env.Merged().Redacted()
+	out.StopTimeout = durationpb.New(c.StopTimeout)
+	out.Critical = c.Critical
+
+	out.BinTarget, err = filepath.EvalSymlinks(svc.bin)
+	if err != nil {
+		// bin may have gone away since svc was loaded, which
+		// shouldn't stop us showing everything else
+		err = nil
+	}
+
+	if c.ReloadSignal != nil {
+		out.ReloadSignal = signalName(c.ReloadSignal.s)
+	}
+
+	if c.StopSignal != nil {
+		out.StopSignal = signalName(c.StopSignal.s)
+	}
+
+	if c.Timeout > 0 {
+		out.Timeout = durationpb.New(c.Timeout)
+
+		if c.TimeoutSignal != nil {
+			out.TimeoutSignal = signalName(c.TimeoutSignal.s)
+		}
+	}
+
+	if c.Cron != nil {
+		out.Cron = &dispatcher.CronConfig{
+			Schedule:    c.Cron.Schedule.String(),
+			Overlap:     c.Cron.Overlap.String(),
+			CatchUp:     c.Cron.CatchUp,
+			TimeZone:    c.Cron.TimeZone,
+			RandomDelay: durationpb.New(c.Cron.RandomDelay),
+		}
+	}
+
+	if c.Oneoff != nil {
+		out.ValidExitCodes = make([]int32, len(c.Oneoff.ValidCodes))
+		for i, code := range c.Oneoff.ValidCodes {
+			out.ValidExitCodes[i] = int32(code)
+		}
+	}
+
+	if c.Getty != nil {
+		out.Getty = &dispatcher.GettyConfig{
+			Tty:  c.Getty.TTYs,
+			Baud: int32(c.Getty.Baud),
+			Term: c.Getty.Term,
+		}
+
+		// gettys on several ttys are split into an instance
+		// per tty
+		if svc.tty != "" {
+			out.Getty.Tty = []string{svc.tty}
+		}
+	}
+
+	if c.Socket != nil {
+		out.Socket = &dispatcher.SocketConfig{
+			Listen: make([]string, len(c.Socket.Listen)),
+			Lazy:   c.Socket.Lazy,
+		}
+
+		for i, addr := range c.Socket.Listen {
+			out.Socket.Listen[i] = addr.String()
+		}
+	}
+
+	if c.Trigger != nil {
+		out.Trigger = &dispatcher.TriggerConfig{
+			Path:     c.Trigger.Paths,
+			OnBoot:   durationpb.New(time.Duration(c.Trigger.OnBoot)),
+			Interval: durationpb.New(c.Trigger.Interval),
+		}
+	}
+
+	return
+}
+
+// groupOf returns the group the service name is booted as part of
+func (s *Supervisor) groupOf(name string) string {
+	_, groupsServices, _ := s.loaded()
+
+	for group, services := range groupsServices {
+		if contains(services, name) {
+			return group
+		}
+	}
+
+	return ""
+}
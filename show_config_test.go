@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestDispatcher_ShowConfig(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		s           *dispatcher.Service
+		expectError error
+	}{
+		{"nil service", nil, errNoService},
+		{"empty service", &dispatcher.Service{}, errNoService},
+		{"unknown service", &dispatcher.Service{Name: "nonesuch"}, errServiceNotExist},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.ShowConfig(context.Background(), test.s)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+
+	t.Run("resolved config", func(t *testing.T) {
+		c, err := d.ShowConfig(context.Background(), &dispatcher.Service{Name: "app"})
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		for _, test := range []struct {
+			name   string
+			expect interface{}
+			got    interface{}
+		}{
+			{"type", "service", c.Type},
+			{"group", "system", c.Group},
+			{"user", "jspc", c.User},
+			{"stop signal", "SIGTERM", c.StopSignal},
+			{"stop timeout", defaultStopTimeout, c.StopTimeout.AsDuration()},
+			{"args", []string{"-a", "-b", "-c", "100"}, c.Args},
+			{"environment", []string{"PATH=/bin:/sbin", "HELLO=world"}, c.Environment},
+		} {
+			if !reflect.DeepEqual(test.expect, test.got) {
+				t.Errorf("%s: expected %v, received %v", test.name, test.expect, test.got)
+			}
+		}
+
+		if !strings.HasSuffix(c.BinTarget, "/testdata/services/00-app/bin") {
+			t.Errorf("expected bin target in testdata, received %q", c.BinTarget)
+		}
+	})
+
+	t.Run("broken config", func(t *testing.T) {
+		c, err := d.ShowConfig(context.Background(), &dispatcher.Service{Name: "broken"})
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		if c.LoadError == "" {
+			t.Error("expected load error")
+		}
+
+		if c.Type != "" {
+			t.Errorf("expected no type, received %q", c.Type)
+		}
+	})
+}
+
+func TestDispatcher_Status_LoadError(t *testing.T) {
+	d := newDispatcher()
+
+	status, err := d.Status(context.Background(), &dispatcher.Service{Name: "broken"})
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	if !strings.Contains(status.LoadError, "nuffin") {
+		t.Errorf("expected load error to mention %q, received %q", "nuffin", status.LoadError)
+	}
+}
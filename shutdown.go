@@ -1,59 +1,708 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
+	"unsafe"
 
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 var (
-	rebooter RebootFunc = syscall.Reboot
-	syncer   SyncFunc   = syscall.Sync
+	rebooter  RebootFunc    = reboot
+	syncer    SyncFunc      = syscall.Sync
+	killer    KillFunc      = unix.Kill
+	survivors SurvivorsFunc = survivingProcesses
+	unmounter UnmountFunc   = unix.Unmount
+	swapoffer SwapoffFunc   = swapoff
+
+	// shutdownReminders are how long before a scheduled shutdown
+	// logged in users are reminded of it, longest first
+	shutdownReminders = []time.Duration{
+		time.Hour,
+		15 * time.Minute,
+		5 * time.Minute,
+		time.Minute,
+	}
+
+	consoleFile = "/dev/console"
+	swapsFile   = "/proc/swaps"
+
+	// pfKthread is the flag in /proc/<pid>/stat marking a process as a
+	// kernel thread
+	pfKthread uint64 = 0x00200000
+
+	// killPollInterval is how often we check whether processes are
+	// still around after being sent SIGTERM
+	killPollInterval = 100 * time.Millisecond
+
+	// virtualFSTypes are filesystems with nothing to flush to disk, and
+	// which are left mounted on shutdown
+	virtualFSTypes = map[string]bool{
+		"autofs":      true,
+		"binfmt_misc": true,
+		"bpf":         true,
+		"cgroup":      true,
+		"cgroup2":     true,
+		"configfs":    true,
+		"debugfs":     true,
+		"devpts":      true,
+		"devtmpfs":    true,
+		"efivarfs":    true,
+		"fusectl":     true,
+		"hugetlbfs":   true,
+		"mqueue":      true,
+		"proc":        true,
+		"pstore":      true,
+		"rootfs":      true,
+		"securityfs":  true,
+		"sysfs":       true,
+		"tmpfs":       true,
+		"tracefs":     true,
+	}
 )
 
 const (
 	// the following come from https://github.com/torvalds/linux/blob/master/include/uapi/linux/reboot.h
 	restart  = 0x01234567
+	restart2 = 0xA1B2C3D4
 	poweroff = 0x4321FEDC
 	halt     = 0xCDEF0123
+
+	defaultShutdownTimeout = 90 * time.Second
+	defaultKillTimeout     = 5 * time.Second
 )
 
 // RebootFunc allows us to stub out syscall behaviour in tests, to avoid accidentally
-// shutting down test machines
-type RebootFunc func(int) error
+// shutting down test machines. arg is only used by restart2
+type RebootFunc func(cmd int, arg string) error
 
 // SyncFunc allows us to stub out calls to sync
 type SyncFunc func()
 
+// KillFunc allows us to stub out sending signals in tests, which is
+// especially important given we signal every process on the box
+type KillFunc func(int, syscall.Signal) error
+
+// SurvivorsFunc allows us to stub out finding which processes are
+// still around after being signalled
+type SurvivorsFunc func() ([]int, error)
+
+// UnmountFunc allows us to stub out unmounting filesystems in tests
+type UnmountFunc func(string, int) error
+
+// SwapoffFunc allows us to stub out disabling swap in tests
+type SwapoffFunc func(string) error
+
 // Shutdown will stop all services nicely, in reverse group/ priority order
 // and then sends a shutdown signal to the kernel
-func (d Dispatcher) Shutdown(context.Context, *emptypb.Empty) (out *emptypb.Empty, err error) {
-	err = d.s.StopAll()
+func (d Dispatcher) Shutdown(ctx context.Context, _ *emptypb.Empty) (out *emptypb.Empty, err error) {
+	return d.ScheduleShutdown(ctx, new(dispatcher.ShutdownRequest))
+}
+
+// Reboot will stop all services nicely, in reverse group/ priority order
+// and then sends a reboots signal
+func (d Dispatcher) Reboot(ctx context.Context, _ *emptypb.Empty) (out *emptypb.Empty, err error) {
+	return d.ScheduleReboot(ctx, new(dispatcher.ShutdownRequest))
+}
+
+// ScheduleShutdown shuts down as Shutdown does, either immediately or at
+// the time requested
+func (d Dispatcher) ScheduleShutdown(_ context.Context, r *dispatcher.ShutdownRequest) (out *emptypb.Empty, err error) {
+	return new(emptypb.Empty), d.s.scheduleShutdown(PowerAction{Cmd: poweroff}, shutdownTime(r, time.Now()), r.GetMessage())
+}
+
+// ScheduleReboot reboots as Reboot does, either immediately or at the
+// time requested
+func (d Dispatcher) ScheduleReboot(_ context.Context, r *dispatcher.ShutdownRequest) (out *emptypb.Empty, err error) {
+	out = new(emptypb.Empty)
+
+	a, err := rebootAction(r.GetRebootOptions())
 	if err != nil {
 		return
 	}
 
+	return out, d.s.scheduleShutdown(a, shutdownTime(r, time.Now()), r.GetMessage())
+}
+
+// rebootAction returns what to ask the kernel to do for the reboot
+// described by o
+func rebootAction(o *dispatcher.RebootOptions) (a PowerAction, err error) {
+	switch o.GetMode() {
+	case dispatcher.RebootMode_KEXEC:
+		if !kexecLoaded() {
+			return a, errNoKexecKernel
+		}
+
+		return PowerAction{Cmd: kexec}, nil
+
+	case dispatcher.RebootMode_RESTART2:
+		if o.GetArgument() == "" {
+			return a, errNoRebootArgument
+		}
+
+		return PowerAction{Cmd: restart2, Arg: o.GetArgument()}, nil
+
+	case dispatcher.RebootMode_FIRMWARE:
+		if !firmwareSetupSupported() {
+			return a, errNoFirmwareSetup
+		}
+
+		return PowerAction{Cmd: restart, Firmware: true}, nil
+	}
+
+	return PowerAction{Cmd: restart}, nil
+}
+
+// CancelShutdown cancels a scheduled shutdown or reboot
+func (d Dispatcher) CancelShutdown(context.Context, *emptypb.Empty) (out *emptypb.Empty, err error) {
+	return new(emptypb.Empty), d.s.cancelShutdown()
+}
+
+// Halt will aggressively halt the system without bothering to stop anything or even
+// syncing
+func (d Dispatcher) Halt(context.Context, *emptypb.Empty) (out *emptypb.Empty, err error) {
+	return new(emptypb.Empty), rebooter(halt, "")
+}
+
+// PowerAction is what the kernel is asked to do once everything
+// has been shut down
+type PowerAction struct {
+	// Cmd is the reboot(2) command to send
+	Cmd int
+
+	// Arg is passed to the kernel alongside restart2
+	Arg string
+
+	// Firmware requests that the machine boots into firmware
+	// setup when it comes back up
+	Firmware bool
+}
+
+// String returns a human readable description of a
+func (a PowerAction) String() string {
+	switch a.Cmd {
+	case restart:
+		if a.Firmware {
+			return "reboot into firmware setup"
+		}
+
+		return "reboot"
+	case restart2:
+		return fmt.Sprintf("reboot (%s)", a.Arg)
+	case halt:
+		return "halt"
+	case kexec:
+		return "kexec reboot"
+	}
+
+	return "poweroff"
+}
+
+// PendingShutdown is a shutdown or reboot scheduled for some point
+// in the future
+type PendingShutdown struct {
+	PowerAction
+
+	At      time.Time
+	Message string
+
+	cancel chan struct{}
+}
+
+// announcement is the message broadcast to logged in users about p
+func (p PendingShutdown) announcement() string {
+	out := fmt.Sprintf("The system is going down for %s at %s!", p.PowerAction, p.At.Format(time.ANSIC))
+	if p.Message != "" {
+		out += "\n\n" + p.Message
+	}
+
+	return out
+}
+
+// shutdownTime returns when the shutdown requested by r should happen;
+// a zero time means now
+func shutdownTime(r *dispatcher.ShutdownRequest, now time.Time) time.Time {
+	if r.GetAt() != nil {
+		return r.GetAt().AsTime()
+	}
+
+	if r.GetDelay() != nil && r.GetDelay().AsDuration() > 0 {
+		return now.Add(r.GetDelay().AsDuration())
+	}
+
+	return time.Time{}
+}
+
+// scheduleShutdown shuts down (or reboots, etc.) at the time at, replacing
+// any shutdown already scheduled. Where at is in the past, this happens
+// immediately
+func (s *Supervisor) scheduleShutdown(a PowerAction, at time.Time, message string) (err error) {
+	s.shutdownMu.Lock()
+
+	if s.pendingShutdown != nil {
+		close(s.pendingShutdown.cancel)
+		s.pendingShutdown = nil
+	}
+
+	if !at.After(time.Now()) {
+		s.shutdownMu.Unlock()
+
+		msg := fmt.Sprintf("The system is going down for %s NOW!", a)
+		if message != "" {
+			msg += "\n\n" + message
+		}
+
+		wall(msg)
+
+		return s.shutdownWith(a)
+	}
+
+	p := &PendingShutdown{
+		PowerAction: a,
+		At:          at,
+		Message:     message,
+		cancel:      make(chan struct{}),
+	}
+
+	s.pendingShutdown = p
+	s.shutdownMu.Unlock()
+
+	wall(p.announcement())
+
+	go s.waitForShutdown(p)
+
+	return
+}
+
+// waitForShutdown waits until p is due, reminding logged in users as it
+// approaches, and then shuts down. It returns early if p is cancelled
+func (s *Supervisor) waitForShutdown(p *PendingShutdown) {
+	for {
+		remaining := time.Until(p.At)
+		if remaining <= 0 {
+			break
+		}
+
+		select {
+		case <-p.cancel:
+			return
+
+		case <-time.After(nextReminder(remaining)):
+		}
+
+		if time.Until(p.At) > 0 {
+			wall(p.announcement())
+		}
+	}
+
+	s.shutdownMu.Lock()
+	if s.pendingShutdown != p {
+		// we've been cancelled or replaced in the meantime
+		s.shutdownMu.Unlock()
+
+		return
+	}
+
+	s.pendingShutdown = nil
+	s.shutdownMu.Unlock()
+
+	wall(fmt.Sprintf("The system is going down for %s NOW!", p.PowerAction))
+
+	err := s.shutdownWith(p.PowerAction)
+	if err != nil {
+		sugar.Errorw("scheduled shutdown failed",
+			"action", p.PowerAction.String(),
+			"error", err.Error(),
+		)
+	}
+}
+
+// nextReminder returns how long to wait before next reminding users of
+// a shutdown due in remaining, or remaining itself when there are no
+// more reminders due
+func nextReminder(remaining time.Duration) time.Duration {
+	for _, r := range shutdownReminders {
+		if remaining > r {
+			return remaining - r
+		}
+	}
+
+	return remaining
+}
+
+// cancelShutdown cancels any pending shutdown, letting logged in
+// users know
+func (s *Supervisor) cancelShutdown() (err error) {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+
+	if s.pendingShutdown == nil {
+		return errNoPendingShutdown
+	}
+
+	close(s.pendingShutdown.cancel)
+	s.pendingShutdown = nil
+
+	wall("The system shutdown has been cancelled")
+
+	return
+}
+
+// shutdown shuts down, passing cmd to the kernel
+func (s *Supervisor) shutdown(cmd int) (err error) {
+	return s.shutdownWith(PowerAction{Cmd: cmd})
+}
+
+// shutdownWith stops all services and, when vinit is PID 1, kills any remaining
+// processes and unmounts filesystems, before syncing disks and carrying out a.
+//
+// Should this take longer than the configured shutdown timeout, vinit
+// gives up waiting and syncs and reboots anyway
+func (s *Supervisor) shutdownWith(a PowerAction) (err error) {
+	out := openProgress()
+	defer out.Close() // #nosec: G307
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	timeout := s.config().Shutdown.Timeout
+
+	go func() {
+		defer close(done)
+
+		s.stopEverything(ctx, out)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		progress(out, "shutdown did not complete within %s, forcing", timeout)
+
+		// services still running are killed, and the remaining
+		// steps skipped, but whichever step is underway must
+		// finish before we can safely sync and reboot
+		cancel()
+		<-done
+	}
+
+	if a.Firmware {
+		progress(out, "requesting firmware setup on next boot")
+
+		err = firmwareSetter()
+		if err != nil {
+			progress(out, "could not request firmware setup: %s", err)
+		}
+	}
+
+	progress(out, "syncing disks")
 	syncer()
 
-	return new(emptypb.Empty), rebooter(poweroff)
+	return rebooter(a.Cmd, a.Arg)
 }
 
-// Reboot will stop all services nicely, in reverse group/ priority order
-// and then sends a reboots signal
-func (d Dispatcher) Reboot(context.Context, *emptypb.Empty) (out *emptypb.Empty, err error) {
-	err = d.s.StopAll()
+// reboot is the default RebootFunc, which extends syscall.Reboot to
+// pass arg to the kernel for restart2
+func reboot(cmd int, arg string) (err error) {
+	if cmd != restart2 {
+		return syscall.Reboot(cmd)
+	}
+
+	p, err := unix.BytePtrFromString(arg)
 	if err != nil {
 		return
 	}
 
-	syncer()
+	_, _, errno := unix.Syscall6(unix.SYS_REBOOT,
+		unix.LINUX_REBOOT_MAGIC1, unix.LINUX_REBOOT_MAGIC2,
+		uintptr(cmd), uintptr(unsafe.Pointer(p)), 0, 0,
+	)
+	if errno != 0 {
+		return errno
+	}
 
-	return new(emptypb.Empty), rebooter(restart)
+	return
 }
 
-// Halt will aggressively halt the system without bothering to stop anything or even
-// syncing
-func (d Dispatcher) Halt(context.Context, *emptypb.Empty) (out *emptypb.Empty, err error) {
-	return new(emptypb.Empty), rebooter(halt)
+// stopEverything stops every service and, when vinit is PID 1, finishes
+// the shutdown. Once ctx is cancelled services are killed rather than
+// waited on, and no further steps are taken
+func (s *Supervisor) stopEverything(ctx context.Context, out io.Writer) {
+	progress(out, "stopping services")
+
+	config := s.config()
+
+	err := s.stopGroups(ctx, reverse(config.Groups))
+	if err != nil {
+		progress(out, "%s", err)
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Killing every process on, and unmounting the filesystems of,
+	// the machine vinit happens to be running on, when vinit isn't
+	// the init process, would be pretty rude
+	if os.Getpid() != 1 {
+		return
+	}
+
+	finishShutdown(ctx, out, config.Shutdown.KillTimeout)
+}
+
+// finishShutdown deals with everything outside of vinit's services which
+// would stop a clean shutdown; stray processes, swap, and mounted
+// filesystems. Each step is skipped once ctx is cancelled
+func finishShutdown(ctx context.Context, out io.Writer, killTimeout time.Duration) {
+	killAll(ctx, out, killTimeout)
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	progress(out, "disabling swap")
+
+	err := swapoffAll()
+	if err != nil {
+		progress(out, "could not disable swap: %s", err)
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	progress(out, "unmounting filesystems")
+
+	err = unmountAll(ctx)
+	if err != nil {
+		progress(out, "could not unmount filesystems: %s", err)
+	}
+}
+
+// killAll sends SIGTERM to every process (bar ourselves), giving them
+// until timeout (or until ctx is cancelled) to exit before sending
+// SIGKILL
+func killAll(ctx context.Context, out io.Writer, timeout time.Duration) {
+	progress(out, "sending SIGTERM to all processes")
+
+	err := killer(-1, unix.SIGTERM)
+	if errors.Is(err, unix.ESRCH) {
+		return
+	}
+
+	// kill(-1, 0) can't tell us when everything has exited, since
+	// it matches kernel threads too, and so always succeeds; we
+	// look for what's left ourselves instead
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		pids, err := survivors()
+		if err == nil && len(pids) == 0 {
+			return
+		}
+
+		time.Sleep(killPollInterval)
+	}
+
+	progress(out, "sending SIGKILL to all processes")
+
+	err = killer(-1, unix.SIGKILL)
+	if err != nil && !errors.Is(err, unix.ESRCH) {
+		progress(out, "could not kill processes: %s", err)
+	}
+}
+
+func survivingProcesses() ([]int, error) {
+	return userProcesses(procDir)
+}
+
+// userProcesses returns the pid of every process under dir, a
+// directory laid out like /proc, which a shutdown needs to see off.
+// As with killall5, that's every process bar ourselves, init, and
+// kernel threads, which have no command line, or are flagged as such
+func userProcesses(dir string) (pids []int, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	pids = make([]int, 0)
+	self := os.Getpid()
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == 1 || pid == self {
+			continue
+		}
+
+		if isKernelThread(filepath.Join(dir, entry.Name())) {
+			continue
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return
+}
+
+// isKernelThread returns true when the process described by dir, such
+// as /proc/2, is a kernel thread, or has exited while we were looking
+func isKernelThread(dir string) bool {
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")) // #nosec: G304
+	if err != nil || len(cmdline) == 0 {
+		return true
+	}
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat")) // #nosec: G304
+	if err != nil {
+		return true
+	}
+
+	// the process name, in parens, may itself contain spaces and
+	// parens, so fields are counted from the last closing paren;
+	// flags is the ninth field, the seventh after the name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	if len(fields) < 7 {
+		return true
+	}
+
+	flags, err := strconv.ParseUint(fields[6], 10, 32)
+	if err != nil {
+		return true
+	}
+
+	return flags&pfKthread != 0
+}
+
+// swapoffAll disables each swap device or file listed in swapsFile. A
+// device failing doesn't stop the rest being tried; every failure is
+// returned together
+func swapoffAll() (err error) {
+	f, err := os.Open(swapsFile) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	var (
+		fields []string
+		errs   []error
+	)
+
+	scanner := bufio.NewScanner(f)
+
+	// skip the header line
+	scanner.Scan()
+
+	for scanner.Scan() {
+		fields = strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		err = swapoffer(unescapeFstab(fields[0]))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fields[0], err))
+		}
+	}
+
+	return errors.Join(append(errs, scanner.Err())...)
 }
+
+// unmountAll unmounts every filesystem in mountsFile which may have
+// something to flush, in the reverse order they were mounted.
+//
+// Filesystems which can't be unmounted (such as the root filesystem)
+// are remounted read-only instead; those which can be neither are
+// returned as errors, together, once every filesystem has been tried
+// (or ctx is cancelled)
+func unmountAll(ctx context.Context) (err error) {
+	f, err := os.Open(mountsFile) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	entries, err := parseFstab(f)
+	f.Close() // #nosec: G104
+
+	if err != nil {
+		return
+	}
+
+	var (
+		e    FstabEntry
+		errs []error
+	)
+
+	for i := len(entries) - 1; i >= 0 && ctx.Err() == nil; i-- {
+		e = entries[i]
+		if virtualFSTypes[e.FSType] {
+			continue
+		}
+
+		if e.Target != "/" && unmounter(e.Target, 0) == nil {
+			continue
+		}
+
+		err = mounter("", e.Target, "", unix.MS_REMOUNT|unix.MS_RDONLY, "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not unmount or remount %s read-only: %w", e.Target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func swapoff(path string) (err error) {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_SWAPOFF, uintptr(unsafe.Pointer(p)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return
+}
+
+// openProgress opens the console for writing shutdown progress to,
+// falling back to discarding progress where the console can't be
+// opened
+func openProgress() io.WriteCloser {
+	f, err := os.OpenFile(consoleFile, os.O_WRONLY|unix.O_NOCTTY, 0) // #nosec: G304
+	if err != nil {
+		return nopCloser{io.Discard}
+	}
+
+	return f
+}
+
+// progress writes a shutdown progress message to both out and
+// the log
+func progress(out io.Writer, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	fmt.Fprintf(out, "vinit: %s\n", msg)
+	sugar.Infow("shutdown: " + msg)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type dummyRebooter struct {
 	cmd int
+	arg string
 }
 
-func (d *dummyRebooter) Reboot(cmd int) error {
+func (d *dummyRebooter) Reboot(cmd int, arg string) error {
 	d.cmd = cmd
+	d.arg = arg
 
 	return nil
 }
@@ -28,6 +41,14 @@ func (d *dummySyncer) Sync() {
 func TestDispatcher_Shutdown(t *testing.T) {
 	d := newDispatcher()
 
+	scheduleShutdown := func(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+		return d.ScheduleShutdown(ctx, nil)
+	}
+
+	scheduleReboot := func(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+		return d.ScheduleReboot(ctx, nil)
+	}
+
 	for _, test := range []struct {
 		name       string
 		cmd        func(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
@@ -37,6 +58,8 @@ func TestDispatcher_Shutdown(t *testing.T) {
 		{"shutdown", d.Shutdown, poweroff, 1},
 		{"reboot", d.Reboot, restart, 1},
 		{"halt", d.Halt, halt, 0},
+		{"schedule shutdown now", scheduleShutdown, poweroff, 1},
+		{"schedule reboot now", scheduleReboot, restart, 1},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := new(dummyRebooter)
@@ -58,3 +81,412 @@ func TestDispatcher_Shutdown(t *testing.T) {
 	}
 
 }
+
+type dummyKiller struct {
+	signals []syscall.Signal
+}
+
+func (d *dummyKiller) Kill(pid int, sig syscall.Signal) error {
+	d.signals = append(d.signals, sig)
+
+	return nil
+}
+
+type dummySurvivors struct {
+	// remaining is the number of times processes are found
+	// before they're considered gone
+	remaining int
+}
+
+func (d *dummySurvivors) Survivors() ([]int, error) {
+	if d.remaining <= 0 {
+		return []int{}, nil
+	}
+
+	d.remaining--
+
+	return []int{100}, nil
+}
+
+type dummyUnmounter struct {
+	unmounted []string
+	busy      map[string]bool
+}
+
+func (d *dummyUnmounter) Unmount(target string, _ int) error {
+	if d.busy[target] {
+		return unix.EBUSY
+	}
+
+	d.unmounted = append(d.unmounted, target)
+
+	return nil
+}
+
+type dummySwapoffer struct {
+	swaps []string
+}
+
+func (d *dummySwapoffer) Swapoff(path string) error {
+	d.swaps = append(d.swaps, path)
+
+	return nil
+}
+
+func TestKillAll(t *testing.T) {
+	killPollInterval = time.Millisecond
+
+	for _, test := range []struct {
+		name      string
+		remaining int
+		expect    []syscall.Signal
+	}{
+		{"processes exit on SIGTERM", 2, []syscall.Signal{unix.SIGTERM}},
+		{"processes ignore SIGTERM", 1000, []syscall.Signal{unix.SIGTERM, unix.SIGKILL}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			k := new(dummyKiller)
+			killer = k.Kill
+
+			sv := &dummySurvivors{remaining: test.remaining}
+			survivors = sv.Survivors
+
+			killAll(context.Background(), io.Discard, 50*time.Millisecond)
+
+			if !reflect.DeepEqual(test.expect, k.signals) {
+				t.Errorf("expected %v, received %v", test.expect, k.signals)
+			}
+		})
+	}
+}
+
+func TestUserProcesses(t *testing.T) {
+	pids, err := userProcesses("testdata/shutdown/proc")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	expect := []int{100, 101}
+	if !reflect.DeepEqual(expect, pids) {
+		t.Errorf("expected %v, received %v", expect, pids)
+	}
+}
+
+func TestFinishShutdown(t *testing.T) {
+	mountsFile = "testdata/shutdown/mounts"
+	swapsFile = "testdata/shutdown/swaps"
+
+	k := new(dummyKiller)
+	killer = k.Kill
+	survivors = new(dummySurvivors).Survivors
+
+	u := &dummyUnmounter{busy: map[string]bool{"/boot": true}}
+	unmounter = u.Unmount
+
+	m := new(dummyMounter)
+	mounter = m.Mount
+
+	sw := new(dummySwapoffer)
+	swapoffer = sw.Swapoff
+
+	finishShutdown(context.Background(), io.Discard, time.Millisecond)
+
+	t.Run("swap is disabled", func(t *testing.T) {
+		expect := []string{"/dev/sda4", "/swap file"}
+		if !reflect.DeepEqual(expect, sw.swaps) {
+			t.Errorf("expected %v, received %v", expect, sw.swaps)
+		}
+	})
+
+	t.Run("filesystems are unmounted in reverse", func(t *testing.T) {
+		expect := []string{"/home"}
+		if !reflect.DeepEqual(expect, u.unmounted) {
+			t.Errorf("expected %v, received %v", expect, u.unmounted)
+		}
+	})
+
+	t.Run("busy and root filesystems are remounted read-only", func(t *testing.T) {
+		expect := []mountCall{
+			{"", "/boot", "", unix.MS_REMOUNT | unix.MS_RDONLY, ""},
+			{"", "/", "", unix.MS_REMOUNT | unix.MS_RDONLY, ""},
+		}
+
+		if !reflect.DeepEqual(expect, m.calls) {
+			t.Errorf("expected %#v, received %#v", expect, m.calls)
+		}
+	})
+}
+
+func TestFinishShutdown_Failures(t *testing.T) {
+	mountsFile = "testdata/shutdown/mounts"
+	swapsFile = "testdata/shutdown/swaps"
+
+	unmounter = func(string, int) error { return unix.EBUSY }
+	mounter = func(_, target, _ string, _ uintptr, _ string) error {
+		if target == "/boot" {
+			return unix.EBUSY
+		}
+
+		return nil
+	}
+
+	var swaps []string
+	swapoffer = func(path string) error {
+		swaps = append(swaps, path)
+
+		return unix.EINVAL
+	}
+
+	t.Run("every swap device is tried", func(t *testing.T) {
+		err := swapoffAll()
+		if err == nil {
+			t.Fatal("expected error, received none")
+		}
+
+		expect := []string{"/dev/sda4", "/swap file"}
+		if !reflect.DeepEqual(expect, swaps) {
+			t.Errorf("expected %v, received %v", expect, swaps)
+		}
+	})
+
+	t.Run("filesystems which can't be remounted are errors", func(t *testing.T) {
+		err := unmountAll(context.Background())
+		if err == nil {
+			t.Fatal("expected error, received none")
+		}
+
+		if !errors.Is(err, unix.EBUSY) {
+			t.Errorf("expected %#v, received %#v", unix.EBUSY, err)
+		}
+	})
+
+	t.Run("nothing is unmounted once out of time", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := unmountAll(ctx)
+		if err != nil {
+			t.Errorf("unexpected error %#v", err)
+		}
+	})
+}
+
+func TestService_Stop_Timeout(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := LoadService("app", filepath.Join(pwd, "testdata/stubborn-services/app"))
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = svc.Start(false)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	// give bash long enough to set its trap
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+
+	err = svc.Stop()
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if time.Since(start) < svc.Config.StopTimeout {
+		t.Errorf("expected service to ignore SIGTERM and be killed after %s", svc.Config.StopTimeout)
+	}
+
+	if svc.isRunning() {
+		t.Errorf("expected service to have stopped")
+	}
+}
+
+func TestSupervisor_shutdown_Timeout(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(filepath.Join(pwd, "testdata/stubborn-services"))
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	r := new(dummyRebooter)
+	rebooter = r.Reboot
+
+	sy := new(dummySyncer)
+	syncer = sy.Sync
+
+	start := time.Now()
+
+	err = s.shutdown(poweroff)
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if time.Since(start) >= s.services["app"].Config.StopTimeout {
+		t.Errorf("expected shutdown to give up after %s", s.Config.Shutdown.Timeout)
+	}
+
+	if r.cmd != poweroff {
+		t.Errorf("expected %X, received %X", poweroff, r.cmd)
+	}
+
+	if sy.syncCount != 1 {
+		t.Errorf("expected 1, received %d", sy.syncCount)
+	}
+
+	if s.services["app"].isRunning() {
+		t.Error("expected service to have been killed before syncing")
+	}
+}
+
+func TestShutdownTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 1, 1, 18, 30, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name   string
+		r      *dispatcher.ShutdownRequest
+		expect time.Time
+	}{
+		{"nil request is now", nil, time.Time{}},
+		{"empty request is now", &dispatcher.ShutdownRequest{}, time.Time{}},
+		{"delay", &dispatcher.ShutdownRequest{Delay: durationpb.New(5 * time.Minute)}, now.Add(5 * time.Minute)},
+		{"absolute time", &dispatcher.ShutdownRequest{At: timestamppb.New(at)}, at},
+		{"absolute time beats delay", &dispatcher.ShutdownRequest{At: timestamppb.New(at), Delay: durationpb.New(time.Minute)}, at},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := shutdownTime(test.r, now)
+			if !test.expect.Equal(got) {
+				t.Errorf("expected %v, received %v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestNextReminder(t *testing.T) {
+	for _, test := range []struct {
+		remaining time.Duration
+		expect    time.Duration
+	}{
+		{2 * time.Hour, time.Hour},
+		{time.Hour, 45 * time.Minute},
+		{10 * time.Minute, 5 * time.Minute},
+		{time.Minute, time.Minute},
+		{30 * time.Second, 30 * time.Second},
+	} {
+		t.Run(test.remaining.String(), func(t *testing.T) {
+			got := nextReminder(test.remaining)
+			if test.expect != got {
+				t.Errorf("expected %s, received %s", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestSupervisor_scheduleShutdown(t *testing.T) {
+	d := newDispatcher()
+
+	// the shutdown happens on a goroutine of its own, and so we need
+	// to hear about it, rather than checking a dummyRebooter
+	rebooted := make(chan int, 1)
+	rebooter = func(cmd int, _ string) error {
+		rebooted <- cmd
+
+		return nil
+	}
+
+	sy := new(dummySyncer)
+	syncer = sy.Sync
+
+	_, err := d.ScheduleReboot(context.Background(), &dispatcher.ShutdownRequest{
+		Delay:   durationpb.New(100 * time.Millisecond),
+		Message: "kernel upgrade",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	t.Run("shutdown is pending", func(t *testing.T) {
+		p := d.systemState().PendingShutdown
+		if p == nil {
+			t.Fatalf("expected pending shutdown, received none")
+		}
+
+		if p.Action != "reboot" {
+			t.Errorf("expected %q, received %q", "reboot", p.Action)
+		}
+
+		if p.Message != "kernel upgrade" {
+			t.Errorf("expected %q, received %q", "kernel upgrade", p.Message)
+		}
+
+		if len(rebooted) != 0 {
+			t.Errorf("expected no reboot yet, received %X", <-rebooted)
+		}
+	})
+
+	t.Run("shutdown happens when due", func(t *testing.T) {
+		select {
+		case cmd := <-rebooted:
+			if cmd != restart {
+				t.Errorf("expected %X, received %X", restart, cmd)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatal("expected reboot, received none")
+		}
+
+		if d.systemState().PendingShutdown != nil {
+			t.Errorf("expected no pending shutdown")
+		}
+	})
+}
+
+func TestSupervisor_cancelShutdown(t *testing.T) {
+	d := newDispatcher()
+
+	r := new(dummyRebooter)
+	rebooter = r.Reboot
+
+	_, err := d.CancelShutdown(context.Background(), nil)
+	if err != errNoPendingShutdown {
+		t.Errorf("expected %v, received %v", errNoPendingShutdown, err)
+	}
+
+	_, err = d.ScheduleShutdown(context.Background(), &dispatcher.ShutdownRequest{
+		Delay: durationpb.New(100 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	_, err = d.CancelShutdown(context.Background(), nil)
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	if r.cmd != 0 {
+		t.Errorf("expected cancelled shutdown not to happen, received %X", r.cmd)
+	}
+
+	if d.systemState().PendingShutdown != nil {
+		t.Errorf("expected no pending shutdown")
+	}
+}
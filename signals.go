@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	SignalAction_Ignore SignalAction = iota
+	SignalAction_Poweroff
+	SignalAction_Reboot
+	SignalAction_Halt
+	SignalAction_Reload
+)
+
+// cadOff, from the same header as restart et al, tells the kernel to send
+// init a SIGINT on Ctrl+Alt+Del, rather than rebooting there and then
+const cadOff = 0x00000000
+
+var (
+	signalCatcher = make(chan os.Signal, 1)
+
+	// signalSupervisor is the supervisor signals act upon. It's set
+	// each time vinit boots, and is nil until the first boot loads
+	// configs
+	signalSupervisor atomic.Pointer[Supervisor]
+
+	// defaultSignalActions are the actions taken on the signals init
+	// is traditionally sent, unless overridden in config
+	defaultSignalActions = map[os.Signal]SignalAction{
+		unix.SIGTERM: SignalAction_Poweroff,
+		unix.SIGINT:  SignalAction_Reboot,
+		unix.SIGPWR:  SignalAction_Poweroff,
+		unix.SIGHUP:  SignalAction_Reload,
+		unix.SIGUSR1: SignalAction_Halt,
+		unix.SIGUSR2: SignalAction_Poweroff,
+	}
+)
+
+// SignalAction governs what vinit does when, as PID 1, it receives
+// a signal; namely:
+//
+//  1. SignalAction_Ignore, represented by "ignore" in config. Do nothing
+//  2. SignalAction_Poweroff, represented by "poweroff" in config. Stop everything and power off
+//  3. SignalAction_Reboot, represented by "reboot" in config. Stop everything and reboot
+//  4. SignalAction_Halt, represented by "halt" in config. Stop everything and halt
+//  5. SignalAction_Reload, represented by "reload" in config. Reload service configs
+type SignalAction int8
+
+// UnmarshalText provides the Unmarshal interface for SignalAction
+func (a *SignalAction) UnmarshalText(text []byte) (err error) {
+	t := string(text)
+
+	switch t {
+	case "ignore":
+		*a = SignalAction_Ignore
+	case "poweroff":
+		*a = SignalAction_Poweroff
+	case "reboot":
+		*a = SignalAction_Reboot
+	case "halt":
+		*a = SignalAction_Halt
+	case "reload":
+		*a = SignalAction_Reload
+	default:
+		err = fmt.Errorf("invalid signal action %q; must be in set (%q,%q,%q,%q,%q)",
+			t, "ignore", "poweroff", "reboot", "halt", "reload")
+	}
+
+	return
+}
+
+// String returns the config representation of a SignalAction
+func (a SignalAction) String() string {
+	switch a {
+	case SignalAction_Poweroff:
+		return "poweroff"
+	case SignalAction_Reboot:
+		return "reboot"
+	case SignalAction_Halt:
+		return "halt"
+	case SignalAction_Reload:
+		return "reload"
+	}
+
+	return "ignore"
+}
+
+// handledSignals returns the signals vinit listens for
+func handledSignals() (sigs []os.Signal) {
+	sigs = make([]os.Signal, 0, len(defaultSignalActions))
+	for sig := range defaultSignalActions {
+		sigs = append(sigs, sig)
+	}
+
+	return
+}
+
+// handledSignalNames returns the names of the signals vinit listens
+// for, in order
+func handledSignalNames() (names []string) {
+	names = make([]string, 0, len(defaultSignalActions))
+	for _, sig := range handledSignals() {
+		names = append(names, signalName(sig))
+	}
+
+	sort.Strings(names)
+
+	return
+}
+
+// handleSignals receives the signals sent to init, acting on each
+// in turn. It should only ever be run when vinit is PID 1
+func handleSignals() {
+	// Have the kernel tell us about Ctrl+Alt+Del, rather than
+	// rebooting without stopping anything
+	err := rebooter(cadOff, "")
+	if err != nil {
+		sugar.Warnw("could not disable ctrl+alt+del",
+			"error", err.Error(),
+		)
+	}
+
+	signal.Notify(signalCatcher, handledSignals()...)
+
+	for sig := range signalCatcher {
+		s := signalSupervisor.Load()
+		if s == nil {
+			sugar.Warnw("received signal before boot, ignoring",
+				"signal", sig.String(),
+			)
+
+			continue
+		}
+
+		err = s.handleSignal(sig)
+		if err != nil {
+			sugar.Errorw("could not handle signal",
+				"signal", sig.String(),
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// handleSignal carries out the action configured for sig
+func (s *Supervisor) handleSignal(sig os.Signal) (err error) {
+	action := s.config().SignalActionFor(sig)
+
+	sugar.Infow("received signal",
+		"signal", sig.String(),
+		"action", action.String(),
+	)
+
+	switch action {
+	case SignalAction_Poweroff:
+		return s.shutdown(poweroff)
+
+	case SignalAction_Reboot:
+		return s.shutdown(restart)
+
+	case SignalAction_Halt:
+		return s.shutdown(halt)
+
+	case SignalAction_Reload:
+		return s.LoadConfigs()
+	}
+
+	return
+}
+
+// signalName returns the name of sig, as used in config
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		return unix.SignalName(s)
+	}
+
+	return sig.String()
+}
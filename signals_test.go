@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSignalAction_UnmarshalText(t *testing.T) {
+	for _, test := range []struct {
+		input       string
+		expect      SignalAction
+		expectError bool
+	}{
+		{"ignore", SignalAction_Ignore, false},
+		{"poweroff", SignalAction_Poweroff, false},
+		{"reboot", SignalAction_Reboot, false},
+		{"halt", SignalAction_Halt, false},
+		{"reload", SignalAction_Reload, false},
+		{"explode", SignalAction_Ignore, true},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			var a SignalAction
+
+			err := a.UnmarshalText([]byte(test.input))
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if test.expect != a {
+				t.Errorf("expected %v, received %v", test.expect, a)
+			}
+		})
+	}
+}
+
+func TestConfig_SignalActionFor(t *testing.T) {
+	c, err := LoadConfig("testdata/successing/signals.toml")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	for _, test := range []struct {
+		sig    os.Signal
+		expect SignalAction
+	}{
+		{unix.SIGPWR, SignalAction_Halt},
+		{unix.SIGTERM, SignalAction_Ignore},
+		{unix.SIGINT, SignalAction_Reboot},
+		{unix.SIGHUP, SignalAction_Reload},
+		{unix.SIGUSR1, SignalAction_Halt},
+		{unix.SIGUSR2, SignalAction_Poweroff},
+		{unix.SIGWINCH, SignalAction_Ignore},
+	} {
+		t.Run(test.sig.String(), func(t *testing.T) {
+			got := c.SignalActionFor(test.sig)
+			if test.expect != got {
+				t.Errorf("expected %v, received %v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_InvalidSignals(t *testing.T) {
+	for _, fn := range []string{
+		"testdata/erroring/invalid-config-signal.toml",
+		"testdata/erroring/invalid-signal-action.toml",
+		"testdata/erroring/unhandled-signal.toml",
+	} {
+		t.Run(fn, func(t *testing.T) {
+			_, err := LoadConfig(fn)
+			if err == nil {
+				t.Errorf("expected error, received none")
+			}
+		})
+	}
+}
+
+func TestSupervisor_handleSignal(t *testing.T) {
+	c, err := LoadConfig("testdata/successing/signals.toml")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	s := &Supervisor{Config: c}
+
+	for _, test := range []struct {
+		sig        os.Signal
+		expectCmd  int
+		expectSync int
+	}{
+		{unix.SIGPWR, halt, 1},
+		{unix.SIGTERM, 0, 0},
+		{unix.SIGINT, restart, 1},
+		{unix.SIGUSR2, poweroff, 1},
+	} {
+		t.Run(test.sig.String(), func(t *testing.T) {
+			r := new(dummyRebooter)
+			rebooter = r.Reboot
+
+			sy := new(dummySyncer)
+			syncer = sy.Sync
+
+			err := s.handleSignal(test.sig)
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if r.cmd != test.expectCmd {
+				t.Errorf("expected %X, received %X", test.expectCmd, r.cmd)
+			}
+
+			if sy.syncCount != test.expectSync {
+				t.Errorf("expected %d, received %d", test.expectSync, sy.syncCount)
+			}
+		})
+	}
+}
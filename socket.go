@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// listenShell sets LISTEN_PID before exec'ing a socket activated
+	// service; the pid of a process isn't known until it's forked
+	listenShell = "/bin/sh"
+
+	// socketPollInterval is how often a lazy service's sockets are
+	// checked for connections, and how often its watcher checks
+	// whether it should still be watching
+	socketPollInterval = time.Second
+)
+
+// listen binds each of the service's listen addresses, should they
+// not already be bound. These listeners are held by vinit, so that
+// they survive the service being restarted
+func (s *Service) listen() (err error) {
+	if s.Config.Socket == nil || len(s.listeners) > 0 {
+		return
+	}
+
+	listeners := make([]*os.File, 0, len(s.Config.Socket.Listen))
+
+	var f *os.File
+	for _, addr := range s.Config.Socket.Listen {
+		f, err = bind(addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close() // #nosec: G104
+			}
+
+			return fmt.Errorf("could not listen on %s: %w", addr, err)
+		}
+
+		listeners = append(listeners, f)
+	}
+
+	s.listeners = listeners
+
+	return
+}
+
+// bind listens on addr, returning the listening socket as a file
+func bind(addr ListenAddress) (f *os.File, err error) {
+	switch addr.Network {
+	case "udp", "udp4", "udp6":
+		var conn net.PacketConn
+
+		conn, err = net.ListenPacket(addr.Network, addr.Address)
+		if err != nil {
+			return
+		}
+
+		defer conn.Close() // #nosec: G307
+
+		return conn.(*net.UDPConn).File()
+
+	case "unix":
+		// a socket left behind by a previous boot would stop us
+		// from binding
+		if fi, statErr := os.Stat(addr.Address); statErr == nil && fi.Mode()&os.ModeSocket != 0 {
+			err = os.Remove(addr.Address)
+			if err != nil {
+				return
+			}
+		}
+
+		var l net.Listener
+
+		l, err = net.Listen(addr.Network, addr.Address)
+		if err != nil {
+			return
+		}
+
+		ul := l.(*net.UnixListener)
+
+		// the socket file must outlive this listener
+		ul.SetUnlinkOnClose(false)
+		defer ul.Close() // #nosec: G307
+
+		return ul.File()
+	}
+
+	l, err := net.Listen(addr.Network, addr.Address)
+	if err != nil {
+		return
+	}
+
+	defer l.Close() // #nosec: G307
+
+	return l.(*net.TCPListener).File()
+}
+
+// closeListeners closes any sockets held for s
+func (s *Service) closeListeners() {
+	s.disarm()
+
+	for _, l := range s.listeners {
+		l.Close() // #nosec: G104
+	}
+
+	s.listeners = nil
+}
+
+// adoptListeners takes over the sockets of old, the previously loaded
+// config of s, so long as s still listens on the same addresses
+func (s *Service) adoptListeners(old *Service) {
+	if len(old.listeners) == 0 {
+		return
+	}
+
+	if s.Config.Socket == nil || !reflect.DeepEqual(s.Config.Socket.Listen, old.Config.Socket.Listen) {
+		old.closeListeners()
+
+		return
+	}
+
+	s.listeners = old.listeners
+}
+
+// passListeners hands s.listeners to proc, in the style of systemd
+// socket activation; they're received from file descriptor 3 onwards,
+// with LISTEN_FDS set to the number of sockets.
+//
+// LISTEN_PID must be the pid of the service itself, which we can't know
+// until after it's forked; instead proc is wrapped in a shell which
+// sets LISTEN_PID to its own pid, and then execs the service
+func (s *Service) passListeners(proc *exec.Cmd) {
+	if len(s.listeners) == 0 {
+		return
+	}
+
+	args := append([]string{"-c", `export LISTEN_PID=$$; exec "$0" "$@"`, proc.Path}, proc.Args[1:]...)
+
+	proc.Path = listenShell
+	proc.Args = append([]string{listenShell}, args...)
+	proc.ExtraFiles = s.listeners
+	proc.Env = append(proc.Env[:len(proc.Env):len(proc.Env)], "LISTEN_FDS="+strconv.Itoa(len(s.listeners)))
+}
+
+func (s *Service) watchSockets(listeners []*os.File, disarmed chan struct{}) {
+	fds := make([]unix.PollFd, len(listeners))
+	for i, l := range listeners {
+		fds[i] = unix.PollFd{Fd: int32(l.Fd()), Events: unix.POLLIN}
+	}
+
+	for !isClosed(disarmed) {
+		// a running service accepts its own connections
+		if s.isRunning() {
+			select {
+			case <-disarmed:
+			case <-time.After(socketPollInterval):
+			}
+
+			continue
+		}
+
+		n, err := unix.Poll(fds, int(socketPollInterval.Milliseconds()))
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			sugar.Errorw("could not watch sockets",
+				"service", s.Name,
+				"error", err.Error(),
+			)
+
+			return
+		}
+
+		if n == 0 || isClosed(disarmed) {
+			continue
+		}
+
+		sugar.Infow("connection received, starting",
+			"service", s.Name,
+		)
+
+		err = s.Start(false)
+		if err != nil {
+			sugar.Errorw("could not start socket activated service",
+				"service", s.Name,
+				"error", err.Error(),
+			)
+
+			// don't spin on a connection we can't serve
+			time.Sleep(socketPollInterval)
+		}
+	}
+}
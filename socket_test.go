@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListenAddress_UnmarshalText(t *testing.T) {
+	for _, test := range []struct {
+		text        string
+		expect      ListenAddress
+		expectError bool
+	}{
+		{"tcp://127.0.0.1:8080", ListenAddress{"tcp", "127.0.0.1:8080"}, false},
+		{"udp6://[::1]:53", ListenAddress{"udp6", "[::1]:53"}, false},
+		{"unix:///run/app.sock", ListenAddress{"unix", "/run/app.sock"}, false},
+		{"127.0.0.1:8080", ListenAddress{}, true},
+		{"tcp://", ListenAddress{}, true},
+		{"sctp://127.0.0.1:8080", ListenAddress{}, true},
+	} {
+		t.Run(test.text, func(t *testing.T) {
+			var l ListenAddress
+
+			err := l.UnmarshalText([]byte(test.text))
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if test.expect != l {
+				t.Errorf("expected %#v, received %#v", test.expect, l)
+			}
+		})
+	}
+}
+
+func TestService_Sockets(t *testing.T) {
+	socketPollInterval = time.Millisecond * 10
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/sockets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	// bind somewhere we can write to, rather than /run
+	dir := t.TempDir()
+	for name, svc := range s.services {
+		svc.Config.Socket.Listen = []ListenAddress{{"unix", filepath.Join(dir, name+".sock")}}
+	}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	lazy := s.services["lazy-app"]
+	eager := s.services["eager-app"]
+
+	t.Run("eager services are started with their sockets", func(t *testing.T) {
+		if !eager.isRunning() {
+			t.Error("service eager-app should be running")
+		}
+	})
+
+	t.Run("lazy services wait for a connection", func(t *testing.T) {
+		if lazy.isRunning() {
+			t.Error("service lazy-app should not be running")
+		}
+	})
+
+	conn, err := net.Dial("unix", filepath.Join(dir, "lazy-app.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	defer conn.Close()
+
+	time.Sleep(time.Millisecond * 100)
+
+	t.Run("connections start lazy services", func(t *testing.T) {
+		if !lazy.isRunning() {
+			t.Error("service lazy-app should be running")
+		}
+	})
+
+	t.Run("sockets are held across restarts", func(t *testing.T) {
+		listeners := lazy.listeners
+
+		err := s.Restart("lazy-app", RestartMode_Restart)
+		if err != nil {
+			t.Fatalf("unexpected error %#v", err)
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		if !reflect.DeepEqual(listeners, lazy.listeners) {
+			t.Error("expected sockets to be reused")
+		}
+
+		if !lazy.isRunning() {
+			t.Error("service lazy-app should be running")
+		}
+	})
+}
@@ -32,7 +32,7 @@ func TestService_Run(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 200)
 
-	if s.status.Error != nil {
-		t.Errorf("unexpected error %#v", s.status.Error)
+	if statusOf(s).Error != nil {
+		t.Errorf("unexpected error %#v", statusOf(s).Error)
 	}
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -12,11 +17,39 @@ var (
 )
 
 type Supervisor struct {
-	Config Config
-
-	dir            string
+	// Config, groupsServices, and services are replaced by each call
+	// to LoadConfigs, rather than changed in place, and are guarded
+	// by configMu. They're read through Supervisor.config,
+	// Supervisor.service, and Supervisor.loaded
+	Config         Config
 	groupsServices map[string][]string
 	services       map[string]*Service
+	configMu       sync.RWMutex
+
+	// loadMu stops LoadConfigs from racing itself, such as when
+	// SIGHUP is sent during a call to ReadConfigs
+	loadMu sync.Mutex
+
+	dir string
+
+	// bootFailure is set by StartAll when a critical service
+	// fails to start
+	bootFailure *BootFailure
+
+	// pendingShutdown is set when a shutdown or reboot is
+	// scheduled for the future, and is guarded by shutdownMu
+	pendingShutdown *PendingShutdown
+	shutdownMu      sync.Mutex
+
+	// target is the target selected at boot, or most recently
+	// isolated, and is guarded by targetMu. When empty, the
+	// configured default target is used
+	target   string
+	targetMu sync.Mutex
+
+	// bootMasks are the services masked for this boot only, via
+	// vinit.mask on the kernel command line
+	bootMasks []string
 }
 
 type ConfigParseError struct {
@@ -41,6 +74,69 @@ func (c ConfigParseError) Error() string {
 	return out.String()
 }
 
+// GroupStartError holds any errors returned by services which failed
+// to start as part of a group
+type GroupStartError struct {
+	group  string
+	errors map[string]error
+}
+
+func (g *GroupStartError) Append(svc string, err error) {
+	if g.errors == nil {
+		g.errors = make(map[string]error)
+	}
+
+	g.errors[svc] = err
+}
+
+func (g GroupStartError) Error() string {
+	out := new(strings.Builder)
+	out.WriteString("the following service(s) in group " + g.group + " failed to start:\n")
+	for svc, err := range g.errors {
+		out.WriteString(svc + ": " + err.Error() + "\n")
+	}
+
+	return out.String()
+}
+
+// StopError holds any errors returned by services which failed
+// to stop
+type StopError struct {
+	errors map[string]error
+}
+
+func (e *StopError) Append(svc string, err error) {
+	if e.errors == nil {
+		e.errors = make(map[string]error)
+	}
+
+	e.errors[svc] = err
+}
+
+func (e StopError) Error() string {
+	out := new(strings.Builder)
+	out.WriteString("the following service(s) failed to stop:\n")
+	for svc, err := range e.errors {
+		out.WriteString(svc + ": " + err.Error() + "\n")
+	}
+
+	return out.String()
+}
+
+// BootFailure is returned by StartAll when a critical service fails
+// to start, and describes what vinit should do about it
+type BootFailure struct {
+	Group   string
+	Service string
+	Action  FailureAction
+	Err     error
+	Time    time.Time
+}
+
+func (b BootFailure) Error() string {
+	return fmt.Sprintf("critical service %s in group %s failed to start: %s", b.Service, b.Group, b.Err)
+}
+
 func New(dir string) (s *Supervisor, err error) {
 	s = &Supervisor{
 		dir: dir,
@@ -51,12 +147,19 @@ func New(dir string) (s *Supervisor, err error) {
 	return
 }
 
+// LoadConfigs (re)loads the supervisor's config, and that of each
+// service, taking over the state of services already loaded
 func (s *Supervisor) LoadConfigs() (err error) {
-	s.Config, err = LoadConfig(filepath.Join(s.dir, ".config.toml"))
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	config, err := LoadConfig(filepath.Join(s.dir, ".config.toml"))
 	if err != nil {
 		return
 	}
 
+	_, _, oldServices := s.loaded()
+
 	groupsServices := make(map[string][]string)
 	services := make(map[string]*Service)
 
@@ -82,7 +185,7 @@ func (s *Supervisor) LoadConfigs() (err error) {
 
 		var groupName string
 		if svc.loadError == "" {
-			groupName = s.Config.ReconcileOverride(name, svc.Config.Grouping.GroupName)
+			groupName = config.ReconcileOverride(name, svc.Config.Grouping.GroupName)
 		} else {
 			groupName = ""
 		}
@@ -92,20 +195,42 @@ func (s *Supervisor) LoadConfigs() (err error) {
 			groupsServices[groupName] = make([]string, 0)
 		}
 
-		groupsServices[groupName] = append(groupsServices[groupName], name)
+		for _, instance := range svc.instances() {
+			sugar.Debugw("loaded service",
+				"service", instance.Name,
+				"group", groupName,
+				"error", instance.loadError,
+			)
 
-		// If this service already exists/ has some state then copy it over
-		// (so we don't lose running state)
-		oldSvc := s.services[name]
-		if oldSvc != nil {
-			svc.status = oldSvc.status
-		}
+			groupsServices[groupName] = append(groupsServices[groupName], instance.Name)
+
+			// If this service already exists/ has some state then take it over
+			// (so we don't lose running state, nor track of its process)
+			instance.historyConf = config.History
+
+			oldSvc := oldServices[instance.Name]
+			if oldSvc != nil {
+				instance.mu = oldSvc.mu
+				instance.runState = oldSvc.runState
+				instance.rearm(oldSvc)
+			} else if config.History.Persist {
+				if hErr := instance.loadHistory(); hErr != nil {
+					sugar.Warnw("could not load service history",
+						"service", instance.Name,
+						"error", hErr.Error(),
+					)
+				}
+			}
 
-		services[name] = svc
+			services[instance.Name] = instance
+		}
 	}
 
+	s.configMu.Lock()
+	s.Config = config
 	s.groupsServices = groupsServices
 	s.services = services
+	s.configMu.Unlock()
 
 	if len(cpe.errors) > 0 {
 		err = cpe
@@ -114,8 +239,36 @@ func (s *Supervisor) LoadConfigs() (err error) {
 	return
 }
 
+// config returns the supervisor's config, as most recently loaded
+func (s *Supervisor) config() Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	return s.Config
+}
+
+// service returns the service called name, as most recently loaded
+func (s *Supervisor) service(name string) (svc *Service, ok bool) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	svc, ok = s.services[name]
+
+	return
+}
+
+// loaded returns the config, services by group, and services most
+// recently loaded. LoadConfigs replaces these rather than changing
+// them, and so they're safe to read once returned
+func (s *Supervisor) loaded() (c Config, groupsServices map[string][]string, services map[string]*Service) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	return s.Config, s.groupsServices, s.services
+}
+
 func (s *Supervisor) Start(name string, wait bool) error {
-	svc, ok := s.services[name]
+	svc, ok := s.service(name)
 	if !ok {
 		return errServiceNotExist
 	}
@@ -124,11 +277,15 @@ func (s *Supervisor) Start(name string, wait bool) error {
 		return errServiceDodgyConf
 	}
 
+	if s.masked(name) {
+		return errServiceMasked
+	}
+
 	return svc.Start(wait)
 }
 
 func (s *Supervisor) Status(name string) (ServiceStatus, error) {
-	svc, ok := s.services[name]
+	svc, ok := s.service(name)
 	if !ok {
 		return ServiceStatus{}, errServiceNotExist
 	}
@@ -137,7 +294,7 @@ func (s *Supervisor) Status(name string) (ServiceStatus, error) {
 }
 
 func (s *Supervisor) Stop(name string) error {
-	svc, ok := s.services[name]
+	svc, ok := s.service(name)
 	if !ok {
 		return errServiceNotExist
 	}
@@ -146,7 +303,7 @@ func (s *Supervisor) Stop(name string) error {
 }
 
 func (s *Supervisor) Reload(name string) error {
-	svc, ok := s.services[name]
+	svc, ok := s.service(name)
 	if !ok {
 		return errServiceNotExist
 	}
@@ -154,17 +311,27 @@ func (s *Supervisor) Reload(name string) error {
 	return svc.Reload()
 }
 
-func (s *Supervisor) StartAll() {
-	var err error
-
-	for _, group := range s.Config.Groups {
+// StartAll starts each group of the active target in order.
+//
+// Services within a group are started concurrently (bounded by
+// s.Config.MaxParallel), and a group is only considered done once
+// every one of its services has either started or failed.
+//
+// Should a critical service fail, the action configured for its group
+// is consulted; where that action is anything other than
+// FailureAction_Continue, no further groups are started and a
+// BootFailure is returned for the caller to act on
+func (s *Supervisor) StartAll() (err error) {
+	_, groupsServices, _ := s.loaded()
+
+	for _, group := range s.activeGroups() {
 		// Ignore anything with an empty group; this signifies
 		// a config error
 		if group == "" {
 			continue
 		}
 
-		services, ok := s.groupsServices[group]
+		services, ok := groupsServices[group]
 		if !ok {
 			sugar.Errorw("group either has no services or does not exist",
 				"group", group,
@@ -173,13 +340,172 @@ func (s *Supervisor) StartAll() {
 			continue
 		}
 
-		for _, service := range services {
+		services = s.bootable(services)
+		if len(services) == 0 {
+			continue
+		}
+
+		err = s.startGroup(group, services)
+		if err == nil {
+			continue
+		}
+
+		gse := err.(GroupStartError)
+		err = nil
+
+		sugar.Errorw("group did not start cleanly",
+			"group", group,
+			"failures", len(gse.errors),
+		)
+
+		bf := s.criticalFailure(gse)
+		if bf == nil {
+			continue
+		}
+
+		sugar.Errorw("critical service failed to start",
+			"group", bf.Group,
+			"service", bf.Service,
+			"action", bf.Action,
+			"error", bf.Err.Error(),
+		)
+
+		if bf.Action == FailureAction_Continue {
+			continue
+		}
+
+		s.bootFailure = bf
+
+		return *bf
+	}
+
+	return
+}
+
+// masked returns true when name, or the service name is an instance
+// of, is masked, either for this boot or persistently
+func (s *Supervisor) masked(name string) bool {
+	base, _, _ := strings.Cut(name, "@")
+	if contains(s.bootMasks, name) || contains(s.bootMasks, base) {
+		return true
+	}
+
+	svc, ok := s.service(name)
+
+	return ok && svc.isMasked()
+}
+
+// disabled returns true when name isn't to be started on boot
+func (s *Supervisor) disabled(name string) bool {
+	svc, ok := s.service(name)
+
+	return ok && svc.isDisabled()
+}
+
+// bootable returns the services which may be started on boot; that
+// is, those which are neither masked nor disabled
+func (s *Supervisor) bootable(services []string) (out []string) {
+	out = make([]string, 0, len(services))
+
+	for _, svc := range services {
+		switch {
+		case s.masked(svc):
+			sugar.Infow("not starting masked service",
+				"service", svc,
+			)
+
+		case s.disabled(svc):
+			sugar.Infow("not starting disabled service",
+				"service", svc,
+			)
+
+		default:
+			out = append(out, svc)
+		}
+	}
+
+	return
+}
+
+// criticalFailure returns a BootFailure for the first critical service
+// (by name) in gse, or nil if none of the failing services are critical
+func (s *Supervisor) criticalFailure(gse GroupStartError) *BootFailure {
+	failed := make([]string, 0, len(gse.errors))
+	for svc := range gse.errors {
+		failed = append(failed, svc)
+	}
+
+	sort.Strings(failed)
+
+	for _, name := range failed {
+		if svc, ok := s.service(name); !ok || !svc.Config.Critical {
+			continue
+		}
+
+		return &BootFailure{
+			Group:   gse.group,
+			Service: name,
+			Action:  s.config().FailureActionFor(gse.group),
+			Err:     gse.errors[name],
+			Time:    time.Now(),
+		}
+	}
+
+	return nil
+}
+
+// startGroup starts each of services concurrently, returning a
+// GroupStartError containing any service which failed to start
+func (s *Supervisor) startGroup(group string, services []string) error {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		gse = GroupStartError{group: group}
+
+		// sem bounds the number of services starting at once
+		sem = make(chan struct{}, s.config().Parallelism(len(services)))
+	)
+
+	for _, service := range services {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(service string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if svc, ok := s.service(service); ok && svc.onDemand() {
+				err := svc.arm()
+				if err != nil {
+					sugar.Errorw("failed!",
+						"group", group,
+						"service", service,
+						"error", err.Error(),
+					)
+
+					mu.Lock()
+					gse.Append(service, err)
+					mu.Unlock()
+
+					return
+				}
+
+				sugar.Infow("waiting to be started on demand",
+					"group", group,
+					"service", service,
+				)
+
+				return
+			}
+
 			sugar.Infow("starting",
 				"group", group,
 				"service", service,
 			)
 
-			err = s.Start(service, true)
+			err := s.Start(service, true)
 			if err != nil {
 				sugar.Errorw("failed!",
 					"group", group,
@@ -187,40 +513,73 @@ func (s *Supervisor) StartAll() {
 					"error", err.Error(),
 				)
 
-				continue
+				mu.Lock()
+				gse.Append(service, err)
+				mu.Unlock()
+
+				return
 			}
 
 			sugar.Infow("started!",
 				"group", group,
 				"service", service,
 			)
+		}(service)
+	}
 
-		}
+	wg.Wait()
+
+	if len(gse.errors) > 0 {
+		return gse
 	}
+
+	return nil
 }
 
-// StopAll does the opposite of StartAll; it reverses the order of
-// s.Config.Groups, then reverses the order of those services in order
-// to stop them all
+// StopAll stops each running service, in reverse group order.
+//
+// A service failing to stop doesn't stop the rest from being stopped;
+// any errors are returned together once every service has been tried
 func (s *Supervisor) StopAll() (err error) {
+	return s.stopGroups(context.Background(), reverse(s.config().Groups))
+}
+
+// stopGroups stops each running service in groups, in the order given,
+// and each group's services in reverse order. Once ctx is cancelled,
+// services are killed rather than given their stop timeout
+func (s *Supervisor) stopGroups(ctx context.Context, groups []string) (err error) {
 	var svc *Service
 
-	for _, group := range reverse(s.Config.Groups) {
-		for _, svcName := range reverse(s.groupsServices[group]) {
-			svc = s.services[svcName]
+	_, groupsServices, services := s.loaded()
+	se := StopError{}
 
-			if svc == nil || !svc.isRunning() {
+	for _, group := range groups {
+		for _, svcName := range reverse(groupsServices[group]) {
+			svc = services[svcName]
+			if svc == nil {
 				continue
 			}
 
-			err = s.Stop(svcName)
+			// stop lazy services from being started by
+			// connections once stopped
+			svc.disarm()
+
+			if !svc.isRunning() {
+				continue
+			}
+
+			err = svc.StopContext(ctx)
 			if err != nil {
-				return
+				se.Append(svcName, err)
 			}
 		}
 	}
 
-	return
+	if len(se.errors) > 0 {
+		return se
+	}
+
+	return nil
 }
 
 func serviceName(s string) string {
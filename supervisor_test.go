@@ -1,8 +1,11 @@
 package main
 
 import (
+	"os"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -35,3 +38,159 @@ func TestNew_IncorrectConfigs(t *testing.T) {
 		t.Errorf("expected\n%s\n\nreceived\n%s", expect, err.Error())
 	}
 }
+
+func TestSupervisor_startGroup(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/parallel-services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err = s.startGroup("slow", s.groupsServices["slow"])
+	took := time.Since(start)
+
+	t.Run("services start concurrently", func(t *testing.T) {
+		// two services sleeping for half a second each should take
+		// a second to start sequentially
+		if took >= time.Second {
+			t.Errorf("expected group to start in under a second, took %s", took)
+		}
+	})
+
+	t.Run("failures are aggregated", func(t *testing.T) {
+		if err == nil {
+			t.Fatal("expected error, received none")
+		}
+
+		gse, ok := err.(GroupStartError)
+		if !ok {
+			t.Fatalf("unexpected error of type: %T", err)
+		}
+
+		if len(gse.errors) != 1 {
+			t.Errorf("expected 1 error, received %d", len(gse.errors))
+		}
+
+		if _, ok = gse.errors["failing"]; !ok {
+			t.Errorf("expected an error for service %q in %#v", "failing", gse)
+		}
+	})
+}
+
+func TestSupervisor_StartAll_CriticalFailure(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/critical-services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.StartAll()
+	if err == nil {
+		t.Fatal("expected error, received none")
+	}
+
+	bf, ok := err.(BootFailure)
+	if !ok {
+		t.Fatalf("unexpected error of type: %T", err)
+	}
+
+	t.Run("failure describes the critical service", func(t *testing.T) {
+		if bf.Service != "mount-things" {
+			t.Errorf("expected %q, received %q", "mount-things", bf.Service)
+		}
+
+		if bf.Action != FailureAction_Recovery {
+			t.Errorf("expected %q, received %q", FailureAction_Recovery, bf.Action)
+		}
+	})
+
+	t.Run("failure is stored for status calls", func(t *testing.T) {
+		if s.bootFailure == nil {
+			t.Error("expected boot failure to be stored")
+		}
+	})
+
+	t.Run("later groups are not started", func(t *testing.T) {
+		if !statusOf(s.services["app"]).StartTime.IsZero() {
+			t.Error("service app should not have been started")
+		}
+	})
+}
+
+func TestSupervisor_StartAll_CriticalFailureContinues(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/critical-services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Config.OnFailure["filesystems"] = FailureAction_Continue
+
+	err = s.StartAll()
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if statusOf(s.services["app"]).StartTime.IsZero() {
+		t.Error("service app should have been started")
+	}
+}
+
+func TestSupervisor_LoadConfigs_Concurrent(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	// reloading configs, as on SIGHUP, while services are looked
+	// up and listed shouldn't race
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			err := s.LoadConfigs()
+			if err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for _, l := range s.listServices() {
+				if l.svc == nil {
+					t.Errorf("service %s was listed, but not loaded", l.name)
+				}
+
+				_, err := s.Status(l.name)
+				if err != nil {
+					t.Errorf("unexpected error %#v", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
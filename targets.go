@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	errNoTarget       = status.Error(codes.InvalidArgument, "missing target name")
+	errTargetNotExist = status.Error(codes.InvalidArgument, "target does not exist")
+)
+
+// Isolate switches vinit to the named target, stopping every service
+// outside of it and starting those within it
+func (d Dispatcher) Isolate(_ context.Context, t *dispatcher.Target) (out *emptypb.Empty, err error) {
+	out = new(emptypb.Empty)
+
+	if t == nil || t.Name == "" {
+		return out, errNoTarget
+	}
+
+	return out, d.s.Isolate(t.Name)
+}
+
+// Target returns the name of the active target, which is empty
+// where no targets are configured
+func (s *Supervisor) Target() string {
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+
+	if s.target == "" {
+		return s.config().DefaultTarget
+	}
+
+	return s.target
+}
+
+// setTarget sets the target started by StartAll
+func (s *Supervisor) setTarget(target string) (err error) {
+	_, err = s.config().TargetGroups(target)
+	if err != nil {
+		return
+	}
+
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+
+	s.target = target
+
+	return
+}
+
+// activeGroups returns the groups of the active target, in boot order.
+//
+// Where that target has since been removed from config, every group
+// is returned
+func (s *Supervisor) activeGroups() []string {
+	target := s.Target()
+	config := s.config()
+
+	groups, err := config.TargetGroups(target)
+	if err != nil {
+		sugar.Warnw("target no longer exists, using every group",
+			"target", target,
+		)
+
+		return config.Groups
+	}
+
+	return groups
+}
+
+// Isolate stops every running service outside of target, in reverse
+// boot order, before starting each service in target which isn't
+// already running, in boot order.
+//
+// Oneoffs which have already succeeded aren't run again. A critical
+// service failing to start stops any further groups from being started
+func (s *Supervisor) Isolate(target string) (err error) {
+	groups, err := s.config().TargetGroups(target)
+	if err != nil {
+		return
+	}
+
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+
+	sugar.Infow("isolating target",
+		"target", target,
+	)
+
+	err = s.stopGroups(context.Background(), s.groupsOutside(groups))
+	if err != nil {
+		return
+	}
+
+	s.target = target
+
+	var (
+		services []string
+		failed   error
+	)
+
+	for _, group := range groups {
+		services = s.pendingServices(group)
+		if len(services) == 0 {
+			continue
+		}
+
+		err = s.startGroup(group, services)
+		if err == nil {
+			continue
+		}
+
+		gse := err.(GroupStartError)
+		if failed == nil {
+			failed = gse
+		}
+
+		if s.criticalFailure(gse) != nil {
+			break
+		}
+	}
+
+	return failed
+}
+
+// groupsOutside returns every group not in keep, in the order they
+// should be stopped: configured groups in reverse boot order, followed
+// by any others
+func (s *Supervisor) groupsOutside(keep []string) (groups []string) {
+	groups = make([]string, 0)
+	config, groupsServices, _ := s.loaded()
+
+	for _, group := range reverse(config.Groups) {
+		if !contains(keep, group) {
+			groups = append(groups, group)
+		}
+	}
+
+	others := make([]string, 0)
+	for group := range groupsServices {
+		if !contains(keep, group) && !contains(config.Groups, group) {
+			others = append(others, group)
+		}
+	}
+
+	sort.Strings(others)
+
+	return append(groups, others...)
+}
+
+// pendingServices returns the services in group which ought to be
+// started when isolating a target containing it
+func (s *Supervisor) pendingServices(group string) (services []string) {
+	services = make([]string, 0)
+	_, groupsServices, loaded := s.loaded()
+
+	var (
+		svc *Service
+		ok  bool
+	)
+
+	for _, name := range s.bootable(groupsServices[group]) {
+		svc, ok = loaded[name]
+		if !ok {
+			continue
+		}
+
+		status, _ := svc.Status()
+		if svc.isRunning() || (svc.Config.Type == ServiceType_Oneoff && status.Success) {
+			continue
+		}
+
+		services = append(services, name)
+	}
+
+	return
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vinyl-linux/vinit/dispatcher"
+)
+
+func TestConfig_TargetGroups(t *testing.T) {
+	c := Config{
+		Groups: []string{"filesystems", "network", "system"},
+		Targets: map[string][]string{
+			"rescue":     {"filesystems"},
+			"multi-user": {"system", "filesystems", "network"},
+		},
+	}
+
+	for _, test := range []struct {
+		name          string
+		defaultTarget string
+		target        string
+		expect        []string
+		expectError   bool
+	}{
+		{"no target and no default means every group", "", "", []string{"filesystems", "network", "system"}, false},
+		{"no target uses the default", "rescue", "", []string{"filesystems"}, false},
+		{"target overrides the default", "rescue", "multi-user", []string{"filesystems", "network", "system"}, false},
+		{"groups are returned in boot order", "", "multi-user", []string{"filesystems", "network", "system"}, false},
+		{"unknown target errors", "", "nonesuch", nil, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c.DefaultTarget = test.defaultTarget
+
+			got, err := c.TargetGroups(test.target)
+			if test.expectError && err == nil {
+				t.Errorf("expected error, received none")
+			} else if !test.expectError && err != nil {
+				t.Errorf("unexpected error %#v", err)
+			}
+
+			if !reflect.DeepEqual(test.expect, got) {
+				t.Errorf("expected %#v, received %#v", test.expect, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_InvalidTargets(t *testing.T) {
+	for _, fn := range []string{
+		"testdata/erroring/invalid-target-group.toml",
+		"testdata/erroring/invalid-default-target.toml",
+	} {
+		t.Run(fn, func(t *testing.T) {
+			_, err := LoadConfig(fn)
+			if err == nil {
+				t.Errorf("expected error, received none")
+			}
+		})
+	}
+}
+
+func TestSupervisor_Isolate(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	err = s.setTarget("rescue")
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	t.Run("only the boot target is started", func(t *testing.T) {
+		if !s.services["base-app"].isRunning() {
+			t.Error("service base-app should be running")
+		}
+
+		if s.services["extra-app"].isRunning() {
+			t.Error("service extra-app should not be running")
+		}
+	})
+
+	basePid := statusOf(s.services["base-app"]).Pid
+
+	for _, test := range []struct {
+		target      string
+		expectExtra bool
+		expectError error
+	}{
+		{"multi-user", true, nil},
+		{"rescue", false, nil},
+		{"nonesuch", false, errTargetNotExist},
+	} {
+		t.Run(test.target, func(t *testing.T) {
+			err := s.Isolate(test.target)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+
+			time.Sleep(time.Millisecond * 100)
+
+			if test.expectExtra != s.services["extra-app"].isRunning() {
+				t.Errorf("expected extra-app running to be %v", test.expectExtra)
+			}
+
+			if basePid != statusOf(s.services["base-app"]).Pid {
+				t.Error("service base-app should not have been restarted")
+			}
+
+			if s.Target() == "nonesuch" {
+				t.Error("target should not have changed")
+			}
+		})
+	}
+}
+
+func TestDispatcher_Isolate(t *testing.T) {
+	d := newDispatcher()
+
+	for _, test := range []struct {
+		name        string
+		t           *dispatcher.Target
+		expectError error
+	}{
+		{"nil target", nil, errNoTarget},
+		{"empty target", &dispatcher.Target{}, errNoTarget},
+		{"unknown target", &dispatcher.Target{Name: "rescue"}, errTargetNotExist},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := d.Isolate(context.Background(), test.t)
+			if test.expectError != err {
+				t.Errorf("expected %#v, received %#v", test.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// errTimedOut is returned by runs which outlive their service's
+// Timeout
+var errTimedOut = errors.New("timed out")
+
+// watchTimeout times out the run of s with process group pgid, should
+// it still be running once s.Config.Timeout has passed. The returned
+// func stops the watch, returning whether the run timed out, and must
+// be called once the run is over
+func (s *Service) watchTimeout(pgid int, done chan struct{}) (stop func() (timedOut bool)) {
+	t := time.AfterFunc(s.Config.Timeout, func() {
+		s.timeOut(pgid, done)
+	})
+
+	// a timer which can no longer be stopped has already fired
+	return func() bool {
+		return !t.Stop()
+	}
+}
+
+// timeOut sends s.Config.TimeoutSignal to the process group pgid,
+// killing it should it still be running once s.Config.StopTimeout
+// has passed
+func (s *Service) timeOut(pgid int, done chan struct{}) {
+	sugar.Warnw("service timed out, stopping",
+		"service", s.Name,
+		"timeout", s.Config.Timeout.String(),
+	)
+
+	err := unix.Kill(-pgid, s.Config.TimeoutSignal.s.(syscall.Signal))
+	if err != nil && !errors.Is(err, unix.ESRCH) {
+		sugar.Errorw("could not signal timed out service",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+
+	select {
+	case <-done:
+		return
+	case <-time.After(s.Config.StopTimeout):
+	}
+
+	sugar.Warnw("timed out service did not stop in time, killing",
+		"service", s.Name,
+		"timeout", s.Config.StopTimeout.String(),
+	)
+
+	err = unix.Kill(-pgid, unix.SIGKILL)
+	if err != nil && !errors.Is(err, unix.ESRCH) {
+		sugar.Errorw("could not kill timed out service",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+}
+
+// timeoutError returns the error a timed out run of s completes with
+func (s *Service) timeoutError() error {
+	return fmt.Errorf("%w after %s", errTimedOut, s.Config.Timeout)
+}
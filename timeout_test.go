@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_Timeout(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/timeouts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	for _, test := range []string{"hanging-oneoff", "stubborn-oneoff"} {
+		t.Run(test, func(t *testing.T) {
+			svc := s.services[test]
+
+			start := time.Now()
+
+			err := s.Start(test, true)
+			if !errors.Is(err, errTimedOut) {
+				t.Errorf("expected %#v, received %#v", errTimedOut, err)
+			}
+
+			if time.Since(start) > time.Second {
+				t.Errorf("expected run to be stopped quickly, took %s", time.Since(start))
+			}
+
+			if !statusOf(svc).TimedOut {
+				t.Error("expected run to have timed out")
+			}
+
+			if statusOf(svc).Success {
+				t.Error("expected run to have failed")
+			}
+
+			// the process group should be gone, not just the
+			// process we started
+			if groupAlive(statusOf(svc).Pid) {
+				t.Errorf("expected process group %d to have been stopped", statusOf(svc).Pid)
+			}
+		})
+	}
+}
+
+// groupAlive returns true when any process in the process group pgid
+// is still running. Zombies, which may not have been reaped yet, don't
+// count
+func groupAlive(pgid int) bool {
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+
+	for _, fn := range stats {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			continue
+		}
+
+		// fields after the command, which may contain spaces,
+		// are: state ppid pgrp ...
+		fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+
+		if fields[2] == strconv.Itoa(pgid) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// inotifyMask covers a path being created, changed, or removed
+	inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_MODIFY | unix.IN_ATTRIB |
+		unix.IN_DELETE | unix.IN_DELETE_SELF | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_MOVE_SELF
+
+	triggerPath     = "path"
+	triggerBoot     = "boot"
+	triggerInterval = "interval"
+)
+
+var (
+	// triggerPollInterval is how often triggers are checked, and how
+	// often a service's trigger watcher checks whether it should
+	// still be watching
+	triggerPollInterval = time.Second
+
+	uptime UptimeFunc = sysUptime
+)
+
+// UptimeFunc allows us to stub out reading how long the machine has
+// been up in tests
+type UptimeFunc func() (time.Duration, error)
+
+func sysUptime() (d time.Duration, err error) {
+	var info unix.Sysinfo_t
+
+	err = unix.Sysinfo(&info)
+	if err != nil {
+		return
+	}
+
+	return time.Duration(info.Uptime) * time.Second, nil
+}
+
+// watchTriggers starts s each time one of its triggers fires, checking
+// them every interval, until s is disarmed
+func (s *Service) watchTriggers(disarmed chan struct{}, interval time.Duration) {
+	t := s.Config.Trigger
+
+	w, err := newPathWatcher(t.Paths)
+	if err != nil {
+		sugar.Errorw("could not watch trigger paths",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+
+		return
+	}
+
+	defer w.Close()
+
+	var (
+		armedAt   = time.Now()
+		bootFired = t.OnBoot <= 0
+		changed   bool
+	)
+
+	for !isClosed(disarmed) {
+		changed, err = w.Wait(interval)
+		if err != nil {
+			sugar.Errorw("could not watch trigger paths",
+				"service", s.Name,
+				"error", err.Error(),
+			)
+
+			return
+		}
+
+		if isClosed(disarmed) {
+			return
+		}
+
+		switch {
+		case changed:
+			s.trigger(triggerPath)
+
+		case !bootFired && s.bootElapsed(time.Duration(t.OnBoot)):
+			bootFired = true
+
+			s.trigger(triggerBoot)
+
+		case t.Interval > 0 && s.intervalElapsed(t.Interval, armedAt):
+			s.trigger(triggerInterval)
+		}
+	}
+}
+
+// bootElapsed returns true once the machine has been up for d
+func (s *Service) bootElapsed(d time.Duration) bool {
+	up, err := uptime()
+	if err != nil {
+		sugar.Warnw("could not read uptime",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+
+		return false
+	}
+
+	return up >= d
+}
+
+// intervalElapsed returns true when s isn't running, and d has passed
+// since it last finished (or, when it has yet to run, since armedAt)
+func (s *Service) intervalElapsed(d time.Duration, armedAt time.Time) bool {
+	if s.isRunning() {
+		return false
+	}
+
+	status, _ := s.Status()
+
+	last := armedAt
+	if status.EndTime.After(last) {
+		last = status.EndTime
+	}
+
+	return time.Since(last) >= d
+}
+
+// trigger starts s, should it not already be running, recording what
+// triggered it
+func (s *Service) trigger(by string) {
+	if s.isRunning() {
+		return
+	}
+
+	s.setTriggered(by)
+
+	sugar.Infow("triggered, starting",
+		"service", s.Name,
+		"trigger", by,
+	)
+
+	err := s.Start(false)
+	if err != nil {
+		sugar.Errorw("could not start triggered service",
+			"service", s.Name,
+			"error", err.Error(),
+		)
+	}
+}
+
+// pathWatcher uses inotify to watch paths for changes. Since a path may
+// not exist yet, its parent directory is watched too
+type pathWatcher struct {
+	fd int
+
+	// watches maps watch descriptors of parent directories to the
+	// names within them we care about. Watches on the paths
+	// themselves map to nil
+	watches map[int32][]string
+}
+
+func newPathWatcher(paths []string) (w *pathWatcher, err error) {
+	w = &pathWatcher{
+		fd:      -1,
+		watches: make(map[int32][]string),
+	}
+
+	if len(paths) == 0 {
+		return
+	}
+
+	w.fd, err = unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return
+	}
+
+	var wd int
+	for _, p := range paths {
+		wd, err = unix.InotifyAddWatch(w.fd, filepath.Dir(p), inotifyMask)
+		if err != nil {
+			w.Close()
+
+			return
+		}
+
+		w.watches[int32(wd)] = append(w.watches[int32(wd)], filepath.Base(p))
+
+		// the path itself may well not exist yet, which is fine
+		wd, err = unix.InotifyAddWatch(w.fd, p, inotifyMask)
+		if err == nil {
+			w.watches[int32(wd)] = nil
+		}
+
+		err = nil
+	}
+
+	return
+}
+
+// Wait waits up to timeout for a change to one of the watched paths
+func (w *pathWatcher) Wait(timeout time.Duration) (changed bool, err error) {
+	if w.fd < 0 {
+		time.Sleep(timeout)
+
+		return
+	}
+
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err != nil {
+		if errors.Is(err, unix.EINTR) {
+			err = nil
+		}
+
+		return
+	}
+
+	if n == 0 {
+		return
+	}
+
+	buf := make([]byte, 4096)
+
+	n, err = unix.Read(w.fd, buf)
+	if err != nil {
+		if errors.Is(err, unix.EAGAIN) {
+			err = nil
+		}
+
+		return
+	}
+
+	return w.relevant(buf[:n]), nil
+}
+
+// relevant returns true when any of the inotify events in buf concern
+// a watched path
+func (w *pathWatcher) relevant(buf []byte) (changed bool) {
+	var (
+		event *unix.InotifyEvent
+		names []string
+		ok    bool
+		name  string
+	)
+
+	for i := 0; i+unix.SizeofInotifyEvent <= len(buf); i += unix.SizeofInotifyEvent + int(event.Len) {
+		event = (*unix.InotifyEvent)(unsafe.Pointer(&buf[i])) // #nosec: G103
+
+		names, ok = w.watches[event.Wd]
+		if !ok {
+			continue
+		}
+
+		// a watch on a path itself
+		if names == nil {
+			changed = true
+
+			continue
+		}
+
+		start := i + unix.SizeofInotifyEvent
+		name = string(bytes.TrimRight(buf[start:start+int(event.Len)], "\x00"))
+
+		if contains(names, name) {
+			changed = true
+		}
+	}
+
+	return
+}
+
+func (w *pathWatcher) Close() {
+	if w.fd >= 0 {
+		unix.Close(w.fd) // #nosec: G104
+	}
+}
+
+// setTriggered records that s has just been triggered by by, returning
+// when
+func (s *Service) setTriggered(by string) (at time.Time) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.triggeredAt = time.Now()
+	s.triggeredBy = by
+
+	return s.triggeredAt
+}
+
+// lastTrigger returns when s was last triggered, and by what
+func (s *Service) lastTrigger() (at time.Time, by string) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	return s.triggeredAt, s.triggeredBy
+}
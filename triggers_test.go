@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestService_Triggers(t *testing.T) {
+	triggerPollInterval = time.Millisecond * 10
+
+	var up atomic.Int64
+	uptime = func() (time.Duration, error) {
+		return time.Duration(up.Load()), nil
+	}
+
+	defer func() {
+		uptime = sysUptime
+	}()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/triggers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	// watch somewhere we can write to, rather than /run
+	trigger := filepath.Join(t.TempDir(), "path-app.trigger")
+	s.services["path-app"].Config.Trigger.Paths = []string{trigger}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	pathApp := s.services["path-app"]
+	bootApp := s.services["boot-app"]
+	intervalApp := s.services["interval-app"]
+
+	t.Run("triggered services wait for their triggers", func(t *testing.T) {
+		for _, svc := range []*Service{pathApp, bootApp} {
+			if svc.isRunning() {
+				t.Errorf("service %s should not be running", svc.Name)
+			}
+
+			if at, _ := svc.lastTrigger(); !at.IsZero() {
+				t.Errorf("service %s should not have been triggered", svc.Name)
+			}
+		}
+	})
+
+	t.Run("boot triggers fire once the machine has been up long enough", func(t *testing.T) {
+		up.Store(int64(time.Hour))
+
+		time.Sleep(time.Millisecond * 100)
+
+		if !bootApp.isRunning() {
+			t.Error("service boot-app should be running")
+		}
+
+		if _, by := bootApp.lastTrigger(); by != triggerBoot {
+			t.Errorf("expected %q, received %q", triggerBoot, by)
+		}
+	})
+
+	t.Run("path triggers fire when a path is created", func(t *testing.T) {
+		err := os.WriteFile(trigger, []byte("hello"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		if !pathApp.isRunning() {
+			t.Error("service path-app should be running")
+		}
+
+		if _, by := pathApp.lastTrigger(); by != triggerPath {
+			t.Errorf("expected %q, received %q", triggerPath, by)
+		}
+	})
+
+	t.Run("interval triggers fire repeatedly", func(t *testing.T) {
+		first, _ := intervalApp.lastTrigger()
+		if first.IsZero() {
+			t.Fatal("service interval-app should have been triggered")
+		}
+
+		time.Sleep(time.Millisecond * 200)
+
+		if last, _ := intervalApp.lastTrigger(); !last.After(first) {
+			t.Error("service interval-app should have been triggered again")
+		}
+
+		if _, by := intervalApp.lastTrigger(); by != triggerInterval {
+			t.Errorf("expected %q, received %q", triggerInterval, by)
+		}
+	})
+}
+
+func TestService_Triggers_Masked(t *testing.T) {
+	triggerPollInterval = time.Millisecond * 10
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(pwd + "/testdata/triggers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.StopAll()
+
+	svc := s.services["path-app"]
+
+	// keep markers out of testdata, and watch somewhere we can
+	// write to, rather than /run
+	svc.dir = t.TempDir()
+
+	trigger := filepath.Join(t.TempDir(), "path-app.trigger")
+	svc.Config.Trigger.Paths = []string{trigger}
+
+	err = s.StartAll()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	err = s.setMarker("path-app", maskedMarker, true)
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	t.Run("masked services ignore their triggers", func(t *testing.T) {
+		err := os.WriteFile(trigger, []byte("hello"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		if svc.isRunning() {
+			t.Error("service path-app should not be running")
+		}
+	})
+
+	t.Run("masked services cannot be started directly", func(t *testing.T) {
+		err := svc.Start(false)
+		if err != errServiceMasked {
+			t.Errorf("expected %#v, received %#v", errServiceMasked, err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// the following describe the layout of a glibc utmp record on
+	// linux, see: utmp(5)
+	utmpRecordSize  = 384
+	utmpUserProcess = 7
+	utmpLineOffset  = 8
+	utmpLineSize    = 32
+)
+
+var (
+	utmpFile = "/run/utmp"
+	ttyDir   = "/dev"
+)
+
+// wall broadcasts msg to every logged in terminal, in the style
+// of wall(1)
+func wall(msg string) {
+	sugar.Infow("broadcasting message",
+		"message", msg,
+	)
+
+	ttys, err := loggedInTTYs()
+	if err != nil {
+		sugar.Warnw("could not read logged in users",
+			"error", err.Error(),
+		)
+
+		return
+	}
+
+	out := wallMessage(msg, time.Now())
+
+	for _, tty := range ttys {
+		err = writeTTY(filepath.Join(ttyDir, tty), out)
+		if err != nil {
+			sugar.Warnw("could not write to terminal",
+				"tty", tty,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// wallMessage formats msg for broadcast. Terminals may well be in raw
+// mode, and so newlines need carriage returns
+func wallMessage(msg string, now time.Time) []byte {
+	out := fmt.Sprintf("\nBroadcast message from vinit (%s):\n\n%s\n\n", now.Format(time.ANSIC), msg)
+
+	return []byte(strings.ReplaceAll(out, "\n", "\r\n"))
+}
+
+// loggedInTTYs returns the terminal of each user process in utmpFile,
+// relative to /dev
+func loggedInTTYs() (ttys []string, err error) {
+	data, err := os.ReadFile(utmpFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+
+		return
+	}
+
+	ttys = make([]string, 0)
+
+	var (
+		record []byte
+		line   string
+	)
+
+	for i := 0; i+utmpRecordSize <= len(data); i += utmpRecordSize {
+		record = data[i : i+utmpRecordSize]
+
+		// ut_type is a short, followed by padding
+		if int16(record[0])|int16(record[1])<<8 != utmpUserProcess {
+			continue
+		}
+
+		line = string(bytes.TrimRight(record[utmpLineOffset:utmpLineOffset+utmpLineSize], "\x00"))
+		if line == "" || strings.Contains(line, "..") {
+			continue
+		}
+
+		ttys = append(ttys, line)
+	}
+
+	return
+}
+
+func writeTTY(path string, msg []byte) (err error) {
+	// O_NONBLOCK stops a wedged terminal from wedging us, too
+	f, err := os.OpenFile(path, os.O_WRONLY|unix.O_NOCTTY|unix.O_NONBLOCK, 0) // #nosec: G304
+	if err != nil {
+		return
+	}
+
+	defer f.Close() // #nosec: G307
+
+	_, err = f.Write(msg)
+
+	return
+}
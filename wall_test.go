@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// utmpRecord returns a minimal utmp record of type typ on line
+func utmpRecord(typ byte, line string) []byte {
+	record := make([]byte, utmpRecordSize)
+	record[0] = typ
+	copy(record[utmpLineOffset:], line)
+
+	return record
+}
+
+func TestLoggedInTTYs(t *testing.T) {
+	dir := t.TempDir()
+
+	utmp := new(bytes.Buffer)
+	utmp.Write(utmpRecord(2, "~"))                  // boot time
+	utmp.Write(utmpRecord(utmpUserProcess, "tty1")) // a login
+	utmp.Write(utmpRecord(8, "tty2"))               // a dead process
+	utmp.Write(utmpRecord(utmpUserProcess, "pts/0"))
+	utmp.Write(utmpRecord(utmpUserProcess, "../etc/passwd"))
+
+	utmpFile = filepath.Join(dir, "utmp")
+	defer func() { utmpFile = os.DevNull }()
+
+	err := os.WriteFile(utmpFile, utmp.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loggedInTTYs()
+	if err != nil {
+		t.Fatalf("unexpected error %#v", err)
+	}
+
+	expect := []string{"tty1", "pts/0"}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected %v, received %v", expect, got)
+	}
+}
+
+func TestLoggedInTTYs_MissingUtmp(t *testing.T) {
+	utmpFile = "/this/path/does/not/exist/i/bloody/well/hope"
+	defer func() { utmpFile = os.DevNull }()
+
+	got, err := loggedInTTYs()
+	if err != nil {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	if len(got) != 0 {
+		t.Errorf("expected no ttys, received %v", got)
+	}
+}
+
+func TestWall(t *testing.T) {
+	dir := t.TempDir()
+
+	utmpFile = filepath.Join(dir, "utmp")
+	ttyDir = dir
+
+	defer func() {
+		utmpFile = os.DevNull
+		ttyDir = "/dev"
+	}()
+
+	err := os.WriteFile(utmpFile, utmpRecord(utmpUserProcess, "tty1"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stand in for a terminal
+	err = os.WriteFile(filepath.Join(dir, "tty1"), nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wall("going down")
+
+	got, err := os.ReadFile(filepath.Join(dir, "tty1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(got, []byte("\r\ngoing down\r\n")) {
+		t.Errorf("expected message to be written, received %q", got)
+	}
+}
+
+func TestWallMessage(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	expect := "\r\nBroadcast message from vinit (Thu Jan  1 12:00:00 2026):\r\n\r\nhello\r\nworld\r\n\r\n"
+	got := string(wallMessage("hello\nworld", now))
+
+	if expect != got {
+		t.Errorf("expected %q, received %q", expect, got)
+	}
+}